@@ -30,42 +30,39 @@ var listVersionsCmd = &cobra.Command{
 	Short: "List versions of Arenadata products",
 	Long: `Will list the 5 latest ADCM versions on hub.arenadata.io in semver format,
 sorted in descending order.
+Credentials of the registry are read from the docker config, including
+credential helpers, unless passed by flags.
 - --all removes the limitation on the last 5 versions and displays all
-        available versions`,
+        available versions
+- --registry-user specifies the registry username
+- --registry-password-stdin reads the registry password from stdin`,
 	Run: listVersions,
 }
 
 func init() {
 	rootCmd.AddCommand(listVersionsCmd)
 	listVersionsCmd.Flags().BoolP("all", "a", false, "List all versions")
+	registryFlags(listVersionsCmd)
 }
 
 func listVersions(cmd *cobra.Command, _ []string) {
 	logger := log.WithField("command", "adcm-versions")
 
-	distributionRef, err := reference.ParseNormalizedNamed(services.ADCMImage)
+	cred, err := registryCredentials(cmd)
 	if err != nil {
 		logger.Fatal(err)
 	}
 
-	domain := reference.Domain(distributionRef)
-	reg := client.NewRegistryClient(domain)
+	var opts []client.RegistryOption
+	if cred != nil {
+		opts = append(opts, client.WihCredentials(cred))
+	}
 
-	tags, err := reg.Tags(reference.Path(distributionRef))
+	versions, err := imageVersions(services.ADCMImage, opts...)
 	if err != nil {
 		logger.Fatal(err)
 	}
 
-	var versions []semver.Version
-	for _, tag := range tags {
-		ver, err := semver.Parse(tag)
-		if err == nil {
-			versions = append(versions, ver)
-		}
-	}
-
-	semver.Sort(versions)
-
 	i := len(versions) - 1
 	end := 0
 	all, _ := cmd.Flags().GetBool("all")
@@ -80,3 +77,31 @@ func listVersions(cmd *cobra.Command, _ []string) {
 		cmd.Println(versions[i].String())
 	}
 }
+
+// imageVersions returns the semver tags of the image repository in
+// ascending order. Tags of other formats are skipped.
+func imageVersions(image string, opts ...client.RegistryOption) ([]semver.Version, error) {
+	distributionRef, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return nil, err
+	}
+
+	domain := reference.Domain(distributionRef)
+	reg := client.NewRegistryClient(domain, opts...)
+
+	tags, err := reg.Tags(reference.Path(distributionRef))
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []semver.Version
+	for _, tag := range tags {
+		ver, err := semver.Parse(tag)
+		if err == nil {
+			versions = append(versions, ver)
+		}
+	}
+
+	semver.Sort(versions)
+	return versions, nil
+}
@@ -0,0 +1,402 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/arenadata/adcm-installer/internal/services"
+	"github.com/arenadata/adcm-installer/pkg/compose"
+	"github.com/arenadata/adcm-installer/pkg/secrets"
+	"github.com/arenadata/adcm-installer/pkg/vault/unseal"
+	"github.com/arenadata/adcm-installer/pkg/vault/unseal/runner"
+
+	"github.com/docker/compose/v2/pkg/api"
+	composeUtils "github.com/docker/compose/v2/pkg/utils"
+	"github.com/docker/docker/api/types/events"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const (
+	defaultAgentInterval   = 30 * time.Second
+	defaultAgentMaxRetries = 3
+	agentReconnectDelay    = 5 * time.Second
+)
+
+var agentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "Watch the installation and heal it",
+	Long: `Runs in the foreground and watches the events of the project containers. A sealed
+Vault is unsealed with the unseal data stored in x-secrets, after a host reboot
+or a restart of its container. A failed init job is re-run and the
+project is brought up again once the init jobs have completed. Every
+action is logged. An uninitialized Vault is left to adi apply. The state of
+the project is also checked every --interval, so events missed while the agent
+was not running are caught up. The configuration file is re-read before every
+action, the master key is read once at startup. Use adi agent systemd to run
+the agent as a service.
+- --age-key takes the value of the private key in clear text. Has priority over
+            --age-key-file
+- --age-key-file takes the value of the path to the file with the private key
+- --file specifies the path to the configuration file
+- --interval specifies how often the state of the project is checked, 30s by
+             default
+- --max-retries specifies how many times a failed init job is re-run,
+                3 by default
+- --unseal-identity specifies the private key file of a holder of a Vault unseal
+                    key share (init vault-key-recipients). The flag is
+                    repeated until the threshold of shares is reached
+- --unseal-via specifies how the Vault unseal commands are run: container,
+               host or api, the init vault-unseal-via value by default`,
+	Run: runAgent,
+}
+
+func init() {
+	rootCmd.AddCommand(agentCmd)
+
+	ageKeyFlags(agentCmd, "age-key", ageKeyFileName)
+	configFileFlags(agentCmd)
+	agentFlags(agentCmd)
+	unsealIdentityFlags(agentCmd)
+	unsealViaFlags(agentCmd)
+}
+
+func agentFlags(cmd *cobra.Command) {
+	cmd.Flags().Duration("interval", defaultAgentInterval, "Interval of the project state checks")
+	cmd.Flags().Int("max-retries", defaultAgentMaxRetries, "Number of re-runs of a failed init job")
+}
+
+type agent struct {
+	configFile string
+	prjName    string
+	aes        secrets.Secrets
+	identities []*secrets.AgeCrypt
+	unsealVia  string
+	maxRetries int
+	logger     *log.Entry
+
+	// the re-runs of the failed init jobs by service name
+	retries map[string]int
+}
+
+func runAgent(cmd *cobra.Command, _ []string) {
+	logger := log.WithField("command", "agent")
+
+	configFilePath, _ := cmd.Flags().GetString("file")
+	prj, err := readConfigFile(configFilePath)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	a := &agent{
+		configFile: prj.ComposeFiles[0],
+		prjName:    prj.Name,
+		logger:     logger.WithField("project", prj.Name),
+		retries:    map[string]int{},
+	}
+	if a.aes, err = encoder(cmd, prj); err != nil {
+		logger.Fatal(err)
+	}
+	if a.identities, err = unsealIdentities(cmd); err != nil {
+		logger.Fatal(err)
+	}
+	a.unsealVia, _ = cmd.Flags().GetString("unseal-via")
+	if !runner.Valid(a.unsealVia) {
+		logger.Fatalf("unknown --unseal-via %q, one of %v is expected", a.unsealVia, runner.Backends)
+	}
+	a.maxRetries, _ = cmd.Flags().GetInt("max-retries")
+	interval, _ := cmd.Flags().GetDuration("interval")
+	if interval <= 0 {
+		interval = defaultAgentInterval
+	}
+
+	comp, err := compose.NewComposeService()
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err = a.run(ctx, comp, interval); err != nil {
+		logger.Fatal(err)
+	}
+}
+
+// run handles the container events until ctx is done. The event stream is
+// reopened after a failure, e.g. a restart of dockerd.
+func (a *agent) run(ctx context.Context, comp *compose.Compose, interval time.Duration) error {
+	a.logger.Infof("Agent started, watching %s", a.configFile)
+
+	tik := time.NewTicker(interval)
+	defer tik.Stop()
+
+	for {
+		a.reconcile(ctx)
+
+		evCtx, cancel := context.WithCancel(ctx)
+		evs, errs := comp.Events(evCtx, a.prjName)
+
+	EVENTS:
+		for {
+			select {
+			case <-ctx.Done():
+				cancel()
+				a.logger.Info("Agent stopped")
+				return nil
+			case err := <-errs:
+				a.logger.Warnf("Container events stream failed: %v", err)
+				break EVENTS
+			case <-tik.C:
+				a.reconcile(ctx)
+			case ev, ok := <-evs:
+				if !ok {
+					break EVENTS
+				}
+				a.handle(ctx, ev)
+			}
+		}
+		cancel()
+
+		select {
+		case <-ctx.Done():
+			a.logger.Info("Agent stopped")
+			return nil
+		case <-time.After(agentReconnectDelay):
+		}
+	}
+}
+
+func (a *agent) handle(ctx context.Context, ev compose.Event) {
+	a.logger.Debugf("Event %s of %s", ev.Action, ev.Container)
+
+	switch {
+	case ev.AppType == services.VaultName &&
+		(ev.Action == events.ActionStart || ev.Action == events.ActionRestart || ev.Action == events.ActionUnPause):
+		d, err := a.deployment(ctx)
+		if err != nil {
+			a.logger.Error(err)
+			return
+		}
+		a.unsealVault(ctx, d)
+
+	case ev.Action == events.ActionDie && ev.ExitCode != 0:
+		d, err := a.deployment(ctx)
+		if err != nil {
+			a.logger.Error(err)
+			return
+		}
+		if isInitService(d, ev.Service) {
+			a.logger.Warnf("Init job container %s failed with exit code %d", ev.Container, ev.ExitCode)
+			a.rerunInit(ctx, d, []string{ev.Service})
+		}
+	}
+}
+
+// reconcile unseals the sealed Vault nodes and re-runs the failed init
+// containers.
+func (a *agent) reconcile(ctx context.Context) {
+	d, err := a.deployment(ctx)
+	if err != nil {
+		a.logger.Error(err)
+		return
+	}
+
+	a.unsealVault(ctx, d)
+
+	containers, err := d.comp.ProjectContainers(ctx, d.prj.Name)
+	if err != nil {
+		a.logger.Error(err)
+		return
+	}
+
+	var failed []string
+	for _, c := range containers {
+		service := c.Labels[api.ServiceLabel]
+		if c.State != "exited" || !isInitService(d, service) {
+			continue
+		}
+
+		inspect, err := d.comp.Inspect(ctx, c.ID)
+		if err != nil {
+			a.logger.Error(err)
+			continue
+		}
+		if inspect.State.ExitCode != 0 {
+			if a.retries[service] < a.maxRetries {
+				a.logger.Warnf("Init job container %s has exited with code %d",
+					strings.TrimPrefix(inspect.Name, "/"), inspect.State.ExitCode)
+			}
+			failed = append(failed, service)
+		}
+	}
+
+	// the failed init jobs have been fixed by apply meanwhile
+	for service := range a.retries {
+		if !composeUtils.StringContains(failed, service) {
+			delete(a.retries, service)
+		}
+	}
+
+	if len(failed) > 0 {
+		a.rerunInit(ctx, d, failed)
+	}
+}
+
+// deployment re-reads the configuration file, apply may have changed it.
+func (a *agent) deployment(ctx context.Context) (*deployment, error) {
+	prj, err := readConfigFile(a.configFile)
+	if err != nil {
+		return nil, err
+	}
+	if prj.Name != a.prjName {
+		return nil, fmt.Errorf("project of %s has been renamed to %s, restart the agent", a.configFile, prj.Name)
+	}
+
+	d, err := loadDeployment(ctx, prj, a.aes)
+	if err != nil {
+		return nil, err
+	}
+	d.unsealIdentities = a.identities
+	d.unsealVia = a.unsealVia
+	// the failed init jobs are re-run by the agent, up to --max-retries times
+	d.jobRetries = 0
+
+	return d, nil
+}
+
+// unsealVault unseals the sealed running Vault nodes. The nodes of an
+// auto-unsealed Vault unseal themselves.
+func (a *agent) unsealVault(ctx context.Context, d *deployment) {
+	nodes := vaultServices(d.prj)
+	if len(nodes) == 0 {
+		return
+	}
+	primary := nodes[0]
+
+	containers := runningVaultContainers(ctx, d.comp, d.prj.Name)
+	var keys []string
+	for _, node := range nodes {
+		name, ok := containers[node]
+		if !ok {
+			continue
+		}
+
+		r, err := d.vaultRunner(node, name)
+		if err != nil {
+			a.logger.Errorf("%s: %v", node, err)
+			continue
+		}
+
+		status, err := r.Status(ctx)
+		if err != nil {
+			a.logger.Warnf("Read vault status of %s failed: %v", node, err)
+			continue
+		}
+		if !status.Sealed {
+			continue
+		}
+		if !status.Initialized {
+			a.logger.Infof("Vault node %s is not initialized, run adi apply", node)
+			continue
+		}
+		if status.Type != unseal.SealTypeShamir {
+			a.logger.Infof("Vault node %s is sealed, waiting for the %s seal", node, status.Type)
+			continue
+		}
+
+		if keys == nil {
+			if keys, err = a.unsealKeys(d, primary); err != nil {
+				a.logger.Errorf("Vault node %s is sealed: %v", node, err)
+				return
+			}
+		}
+
+		a.logger.Infof("Unsealing Vault node %s", node)
+		if err = r.Unseal(ctx, keys); err != nil {
+			a.logger.Errorf("Unseal of %s failed: %v", node, err)
+			continue
+		}
+		a.logger.Infof("Vault node %s unsealed", node)
+	}
+}
+
+func (a *agent) unsealKeys(d *deployment, primary string) ([]string, error) {
+	unsealDataRaw, ok := d.unMapped[primary][services.VaultUnsealData]
+	if !ok {
+		return nil, fmt.Errorf("no unseal data in %s", a.configFile)
+	}
+
+	// the key shares are decrypted from their x-secrets values
+	unMappedData := map[string]any{}
+	if ext, ok := d.prj.Services[primary].Extensions[services.XSecretsKey]; ok {
+		for k, v := range ext.(*services.XSecrets).UnMapped {
+			unMappedData[k] = v
+		}
+	}
+
+	return vaultUnsealKeys(d.aes, primary, unsealDataRaw, unMappedData, a.identities, vaultInitParams(d.prj))
+}
+
+// rerunInit runs the init jobs again and brings the project up once
+// they have completed. A service is re-run at most maxRetries times.
+func (a *agent) rerunInit(ctx context.Context, d *deployment, failed []string) {
+	for _, service := range failed {
+		if a.retries[service] == a.maxRetries {
+			a.logger.Errorf("Init job of %s has failed %d times, giving up: check its logs and run adi apply",
+				service, a.retries[service]+1)
+			// reported once
+			a.retries[service]++
+		}
+		if a.retries[service] > a.maxRetries {
+			return
+		}
+	}
+	for _, service := range failed {
+		a.retries[service]++
+		a.logger.Infof("Re-running init job of %s (%d/%d)", service, a.retries[service], a.maxRetries)
+	}
+
+	err := d.runJobs(ctx, false)
+	if err != nil {
+		a.logger.Errorf("Init jobs failed: %v", err)
+		return
+	}
+
+	for _, service := range failed {
+		delete(a.retries, service)
+	}
+	a.logger.Info("Init jobs completed, bringing up the project")
+
+	if err = d.comp.Up(ctx, d.prj, true, d.upOptions()...); err != nil {
+		a.logger.Errorf("Bringing up the project failed: %v", err)
+		return
+	}
+	a.logger.Info("Project is up")
+}
+
+// isInitService reports whether the service is an init job.
+func isInitService(d *deployment, service string) bool {
+	_, ok := d.jobs[service]
+	return ok
+}
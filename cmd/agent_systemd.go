@@ -0,0 +1,161 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var agentSystemdCmd = &cobra.Command{
+	Use:   "systemd",
+	Short: "Generate a systemd unit running the agent",
+	Long: `Prints a systemd unit which runs adi agent for the project with the same flags.
+The paths of the configuration file, the private key and the unseal identities
+are made absolute. The private key is read from a file, the agent has no
+terminal to ask for a passphrase: set AGE_KEY_PASSPHRASE with an Environment=
+line of the unit for a passphrase protected key. The unseal identities must
+not be passphrase protected. Install the unit with:
+  adi agent systemd -o /etc/systemd/system/adi-agent-<project>.service
+  systemctl daemon-reload
+  systemctl enable --now adi-agent-<project>
+- --age-key-file takes the value of the path to the file with the private key
+- --file specifies the path to the configuration file
+- --interval specifies how often the state of the project is checked, 30s by
+             default
+- --max-retries specifies how many times a failed init job is re-run,
+                3 by default
+- --output specifies the path of the file to which the unit will be written
+- --unseal-identity specifies the private key file of a holder of a Vault unseal
+                    key share, may be repeated
+- --unseal-via specifies how the Vault unseal commands are run: container,
+               host or api`,
+	Run: agentSystemd,
+}
+
+var agentUnitTemplate = template.Must(template.New("unit").Parse(`[Unit]
+Description=Arenadata installer agent of {{ .Project }}
+Requires=docker.service
+After=docker.service
+
+[Service]
+Type=simple
+WorkingDirectory={{ .WorkingDir }}
+ExecStart={{ .ExecStart }}
+Restart=always
+RestartSec=10
+
+[Install]
+WantedBy=multi-user.target
+`))
+
+func init() {
+	agentCmd.AddCommand(agentSystemdCmd)
+
+	agentSystemdCmd.Flags().String("age-key-file", ageKeyFileName, "Read private age or ssh key from file")
+	configFileFlags(agentSystemdCmd)
+	agentFlags(agentSystemdCmd)
+	unsealIdentityFlags(agentSystemdCmd)
+	unsealViaFlags(agentSystemdCmd)
+	agentSystemdCmd.Flags().StringP("output", "o", "", "Output filename")
+}
+
+func agentSystemd(cmd *cobra.Command, _ []string) {
+	logger := log.WithField("command", "agent-systemd")
+
+	configFilePath, _ := cmd.Flags().GetString("file")
+	prj, err := readConfigFile(configFilePath)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		logger.Fatal(err)
+	}
+	configFile, err := filepath.Abs(prj.ComposeFiles[0])
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	args := []string{exe, "agent", "--file", configFile}
+
+	keyFile, _ := cmd.Flags().GetString("age-key-file")
+	if _, err = os.Stat(keyFile); err == nil {
+		if keyFile, err = filepath.Abs(keyFile); err != nil {
+			logger.Fatal(err)
+		}
+		args = append(args, "--age-key-file", keyFile)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		logger.Fatal(err)
+	}
+
+	ids, _ := cmd.Flags().GetStringArray("unseal-identity")
+	for _, id := range ids {
+		if id, err = filepath.Abs(id); err != nil {
+			logger.Fatal(err)
+		}
+		args = append(args, "--unseal-identity", id)
+	}
+
+	if via, _ := cmd.Flags().GetString("unseal-via"); len(via) > 0 {
+		args = append(args, "--unseal-via", via)
+	}
+	if cmd.Flags().Changed("interval") {
+		interval, _ := cmd.Flags().GetDuration("interval")
+		args = append(args, "--interval", interval.String())
+	}
+	if cmd.Flags().Changed("max-retries") {
+		maxRetries, _ := cmd.Flags().GetInt("max-retries")
+		args = append(args, "--max-retries", strconv.Itoa(maxRetries))
+	}
+
+	for i, arg := range args {
+		args[i] = systemdQuote(arg)
+	}
+
+	closer, err := setOutput(cmd)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	defer func() { _ = closer.Close() }()
+
+	err = agentUnitTemplate.Execute(cmd.OutOrStdout(), map[string]string{
+		"Project":    prj.Name,
+		"WorkingDir": filepath.Dir(configFile),
+		"ExecStart":  strings.Join(args, " "),
+	})
+	if err != nil {
+		logger.Fatal(err)
+	}
+}
+
+// systemdQuote quotes the argument of a unit command line if needed.
+func systemdQuote(s string) string {
+	if len(s) > 0 && !strings.ContainsAny(s, " \t\"'\\$%;") {
+		return s
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `$$`, `%`, `%%`)
+	return fmt.Sprintf(`"%s"`, r.Replace(s))
+}
@@ -18,15 +18,17 @@ package cmd
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net/url"
 	"os"
 	"path"
+	"slices"
+	"sort"
 	"strings"
 	"time"
 
-	"github.com/arenadata/adcm-installer/assets"
 	"github.com/arenadata/adcm-installer/internal/services"
 	"github.com/arenadata/adcm-installer/internal/services/helpers"
 	"github.com/arenadata/adcm-installer/pkg/compose"
@@ -34,7 +36,7 @@ import (
 	"github.com/arenadata/adcm-installer/pkg/types"
 	"github.com/arenadata/adcm-installer/pkg/utils"
 	"github.com/arenadata/adcm-installer/pkg/vault/unseal"
-	"github.com/arenadata/adcm-installer/pkg/vault/unseal/image"
+	"github.com/arenadata/adcm-installer/pkg/vault/unseal/runner"
 
 	"github.com/Masterminds/semver/v3"
 	composeTypes "github.com/compose-spec/compose-go/v2/types"
@@ -65,16 +67,51 @@ initialization are pre-configured (when calling adi init with the --adpg
 argument). Without arguments, the current directory's adcm.yaml
 (adcm.yml/ad-app.yml/ad-app.yaml) and age.key files are searched. If either
 file is missing or has an unknown format, the application will exit with an error.
+The command returns when the services are healthy, ADCM is healthy when its API
+answers with any status but a server error (401 or 403 without credentials).
+The state of the ADCM containers is reported while waiting.
 - --age-key takes the value of the private key in clear text. Has priority over
             --age-key-file
 - --age-key-file takes the value of the path to the file with the private key
 - --dry-run terminates the command without starting containers with the output
             of the configuration for docker compose with encrypted secrets
+- --debug keeps the containers of the init jobs, including the failed ones, for
+          inspection
 - --file specifies the path to the configuration file
+- --init-retries specifies how many times a failed init job is re-run, 1 by
+                 default. The jobs are run in their dependency order, a job
+                 which has already been done with the same configuration is
+                 skipped
+- --max-unavailable specifies how many ADCM instances are recreated at once,
+                    1 by default. The changed instances are recreated batch
+                    by batch before the other services, each batch must
+                    become healthy before the next one is started. On a
+                    failure the rollout halts and the instances not yet
+                    updated are left as they are
 - --output is used together with the --dry-run flag to specify the path of the
-		   file to which the output will be written
+		   file to which the output will be written. With the --plan flag the
+		   plan is written to the file in JSON format
+- --plan terminates the command without starting containers with the list of
+         services which will be created, recreated or removed, same as adi diff
+- --plan-file applies the configuration only if the plan saved in the file
+              with --plan --output is still the one which is going to be
+              executed. The containers of the services missing in the
+              configuration are removed only in this mode, as the plan
+              lists them
 - --pg-debug enables the output of debugging information in the container logs,
-             excluding the output of sensitive data`,
+             excluding the output of sensitive data
+- --unseal-identity specifies the private key file of a holder of a Vault unseal
+                    key share (init vault-key-recipients). The flag is
+                    repeated until the threshold of shares is reached. The
+                    shares are required to unseal Vault after it has been
+                    initialized
+- --unseal-via specifies how the Vault unseal commands are run: container
+               (bao in the Vault container), host (vault or bao binary of
+               the host) or api (HTTP API). The host and api backends connect
+               to the published port of every node. The init
+               vault-unseal-via value is used by default
+- --wait-timeout specifies how long to wait for every init job to
+                 complete and the services to become healthy, 5m by default`,
 		Run: applyProject,
 	}
 )
@@ -86,47 +123,134 @@ func init() {
 	configFileFlags(applyCmd)
 
 	applyCmd.Flags().Bool("dry-run", false, "Simulate an apply command and generate compose files")
-	applyCmd.Flags().Bool("debug", false, "Enable debug in containers")
+	applyCmd.Flags().Bool("debug", false, "Keep the containers of the init jobs")
 	applyCmd.Flags().Bool("force", false, "Rewrite unseal data in x-secrets")
+	applyCmd.Flags().Bool("plan", false, "Show the changes without applying them")
+	applyCmd.Flags().String("plan-file", "", "Apply only if the changes match the saved plan")
 	applyCmd.MarkFlagsMutuallyExclusive("dry-run", "debug")
+	applyCmd.MarkFlagsMutuallyExclusive("dry-run", "plan", "plan-file")
+	applyCmd.MarkFlagsMutuallyExclusive("debug", "plan")
 	applyCmd.Flags().StringP("output", "o", "", "Output filename")
+	unsealIdentityFlags(applyCmd)
+	unsealViaFlags(applyCmd)
+	rolloutFlags(applyCmd)
 }
 
-func applyProject(cmd *cobra.Command, _ []string) {
-	logger := log.WithField("command", "apply")
+func rolloutFlags(cmd *cobra.Command) {
+	cmd.Flags().Int("init-retries", services.DefaultJobRetries, "Number of re-runs of a failed init job")
+	cmd.Flags().Int("max-unavailable", 1, "Number of ADCM instances recreated at once")
+	cmd.Flags().Duration("wait-timeout", defaultWaitTimeout, "Time to wait for the services to become healthy")
+}
+
+const (
+	defaultWaitTimeout = 5 * time.Minute
+	progressInterval   = 10 * time.Second
+	vaultAPITimeout    = 30 * time.Second
+)
+
+type deployment struct {
+	prj      *composeTypes.Project
+	comp     *compose.Compose
+	aes      secrets.Secrets
+	macKey   []byte
+	xSecrets map[string]map[string]string
+	unMapped map[string]map[string]string
+	// the init jobs, they are not services of prj
+	jobs map[string]*services.Job
+
+	maxUnavailable int
+	waitTimeout    time.Duration
+	jobRetries     int
+	// remove the containers of the services missing in prj
+	removeOrphans bool
+
+	// the identities of the unseal key share holders
+	unsealIdentities []*secrets.AgeCrypt
+	// the unseal backend, the init vault-unseal-via value if empty
+	unsealVia string
+}
 
+// newDeployment reads the configuration file and builds the compose project
+// which is going to be run, including the init jobs.
+func newDeployment(cmd *cobra.Command, decrypt bool) (*deployment, error) {
 	configFilePath, _ := cmd.Flags().GetString("file")
 	prj, err := readConfigFile(configFilePath)
 	if err != nil {
-		logger.Fatal(err)
+		return nil, err
 	}
 
-	dryRunMode := getBool(cmd, "dry-run")
-	debugMode := getBool(cmd, "debug")
-	force := getBool(cmd, "force")
-
 	var aes secrets.Secrets
-	if !dryRunMode {
+	if decrypt {
 		aes, err = encoder(cmd, prj)
 		if err != nil {
-			logger.Fatal(err)
+			return nil, err
 		}
 	}
 
+	identities, err := unsealIdentities(cmd)
+	if err != nil {
+		return nil, err
+	}
+	unsealVia, _ := cmd.Flags().GetString("unseal-via")
+	if !runner.Valid(unsealVia) {
+		return nil, fmt.Errorf("unknown --unseal-via %q, one of %v is expected", unsealVia, runner.Backends)
+	}
+
+	d, err := loadDeployment(cmd.Context(), prj, aes)
+	if err != nil {
+		return nil, err
+	}
+	d.maxUnavailable, _ = cmd.Flags().GetInt("max-unavailable")
+	d.waitTimeout, _ = cmd.Flags().GetDuration("wait-timeout")
+	if cmd.Flags().Lookup("init-retries") != nil {
+		d.jobRetries, _ = cmd.Flags().GetInt("init-retries")
+	}
+	d.unsealIdentities = identities
+	d.unsealVia = unsealVia
+
+	return d, nil
+}
+
+// loadDeployment builds the deployment of the project with the secrets
+// decrypted by aes.
+func loadDeployment(ctx context.Context, prj *composeTypes.Project, aes secrets.Secrets) (*deployment, error) {
 	xSecrets, unMappedxSecrets, err := secretsDecrypt(prj.Services, aes)
 	if err != nil {
-		logger.Fatal(err)
+		return nil, err
+	}
+
+	macKey, err := readMacKey(prj, aes)
+	if err != nil {
+		return nil, err
 	}
 
 	execBuf := new(bytes.Buffer)
 	comp, err := compose.NewComposeService(command.WithOutputStream(execBuf))
 	if err != nil {
-		logger.Fatal(err)
+		return nil, err
 	}
 
-	criInfo, err := comp.Info(cmd.Context())
+	d := &deployment{
+		prj:      prj,
+		comp:     comp,
+		aes:      aes,
+		macKey:   macKey,
+		xSecrets: xSecrets,
+		unMapped: unMappedxSecrets,
+
+		jobRetries: services.DefaultJobRetries,
+	}
+	if err = d.build(ctx); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+func (d *deployment) build(ctx context.Context) error {
+	criInfo, err := d.comp.Info(ctx)
 	if err != nil {
-		logger.Fatal(err)
+		return err
 	}
 
 	// https://github.com/moby/moby/blob/v27.5.1/daemon/archive_tarcopyoptions_unix.go#L16
@@ -135,7 +259,7 @@ func applyProject(cmd *cobra.Command, _ []string) {
 	var needSecretsFix bool
 	serverVersion, err := semver.NewVersion(serverVersionString)
 	if err != nil {
-		logger.Warnf("Cannot parse dockerd Server Version %s: %s", criInfo.ServerVersion, err)
+		log.Warnf("Cannot parse dockerd Server Version %s: %s", criInfo.ServerVersion, err)
 		needSecretsFix = true
 	} else {
 		needSecretsFix = serverVersion.LessThan(semver.MustParse("v28.0.0"))
@@ -144,9 +268,9 @@ func applyProject(cmd *cobra.Command, _ []string) {
 	hostOS := criInfo.OperatingSystem
 	servicesModHelpers := helpers.NewModHelpers()
 	pgInit := types.NewPGInit()
-	_, managedAdpg := prj.Services[services.AdpgName]
+	_, managedAdpg := d.prj.Services[services.AdpgName]
 
-	for name, svc := range prj.Services {
+	for name, svc := range d.prj.Services {
 		if needSecretsFix {
 			svc.User = strings.SplitN(svc.User, ":", 2)[0]
 		}
@@ -155,7 +279,7 @@ func applyProject(cmd *cobra.Command, _ []string) {
 		for i, sec := range svc.Secrets {
 			svc.Secrets[i].Source = name + "-" + sec.Source
 		}
-		prj.Services[name] = svc
+		d.prj.Services[name] = svc
 
 		servicesModHelpers = append(servicesModHelpers,
 			helpers.Profiles(name, services.PrimaryContainerProfile),
@@ -169,7 +293,7 @@ func applyProject(cmd *cobra.Command, _ []string) {
 		}
 
 		if appType == services.AdcmName {
-			sec := xSecrets[name]
+			sec := d.xSecrets[name]
 
 			for k, v := range sec {
 				envKey := mapFlagsToEnv[k]
@@ -199,6 +323,13 @@ func applyProject(cmd *cobra.Command, _ []string) {
 				fillPgInitFile(pgInit, sec)
 			}
 
+		} else if appType == services.ProxyName || appType == services.ConsulName {
+			for k, v := range d.xSecrets[name] {
+				servicesModHelpers = append(servicesModHelpers,
+					helpers.ProjectSecrets(helpers.Secret{Source: name + "-" + k, Value: v}),
+				)
+			}
+
 		} else if name == services.AdpgName {
 			if svc.ReadOnly {
 				mntOpts := mountOpt(hostOS, svc.User)
@@ -209,7 +340,7 @@ func applyProject(cmd *cobra.Command, _ []string) {
 					helpers.TmpFs{Target: "/var/run/postgresql", MountOptions: mntOpts}))
 			}
 
-		} else if name == services.VaultName {
+		} else if appType == services.VaultName {
 			vaultMode := svc.Labels[compose.ADVaultModeLabelKey]
 			if len(vaultMode) > 0 && vaultMode != services.VaultDeployModeDev {
 				var target string
@@ -223,59 +354,61 @@ func applyProject(cmd *cobra.Command, _ []string) {
 				servicesModHelpers = append(servicesModHelpers,
 					helpers.Entrypoint(name, "bao", "server", "-config="+target))
 
-				if managedAdpg {
-					sec := xSecrets[name]
-					unMap := unMappedxSecrets[name]
-
-					for k, v := range sec {
-						if k == services.ConfigJson {
-							var configFile services.VaultConfigFile
-							if err = json.Unmarshal([]byte(v), &configFile); err != nil {
-								logger.Fatal(err)
-							}
-
-							u, err := url.Parse(configFile.Storage.Postgresql.ConnectionUrl)
-							if err != nil {
-								logger.Fatal(err)
-							}
-
-							u.Path = unMap[services.PgDbName]
-							u.User = url.UserPassword(unMap[services.PgDbUser], unMap[services.PgDbPass])
-
-							configFile.Storage.Postgresql.ConnectionUrl = u.String()
-							b, err := json.Marshal(configFile)
-							if err != nil {
-								logger.Fatal(err)
-							}
-							v = string(b)
+				unMap := d.unMapped[name]
+				for k, v := range d.xSecrets[name] {
+					switch k {
+					case services.ConfigJson:
+						if v, err = vaultConfigWithCredentials(v, unMap); err != nil {
+							return err
 						}
-
-						s := helpers.Secret{
-							Source: name + "-" + k,
-							Value:  v,
-							Target: path.Join(helpers.SecretsPath, k),
+					case services.VaultSealKey:
+						// the static seal reads the raw key bytes
+						b, err := base64.StdEncoding.DecodeString(v)
+						if err != nil {
+							return fmt.Errorf("%s: %v", k, err)
 						}
+						v = string(b)
+					}
 
-						servicesModHelpers = append(servicesModHelpers,
-							helpers.ProjectSecrets(s),
-						)
+					s := helpers.Secret{
+						Source: name + "-" + k,
+						Value:  v,
+						Target: path.Join(helpers.SecretsPath, k),
 					}
 
+					servicesModHelpers = append(servicesModHelpers,
+						helpers.ProjectSecrets(s),
+					)
+				}
+
+				if managedAdpg {
 					fillPgInitFile(pgInit, unMap)
 				}
 			}
 		}
 	}
 
+	// the data volumes are owned by root or by the image user, not by the
+	// user of the service
+	for _, name := range d.prj.ServiceNames() {
+		svc := d.prj.Services[name]
+		switch svc.Labels[compose.ADAppTypeLabelKey] {
+		case services.ConsulName, services.VaultName:
+			if len(svc.Volumes) > 0 {
+				services.ChownContainer(d.prj, svc)
+			}
+		}
+	}
+
 	if managedAdpg {
-		svc := prj.Services[services.AdpgName]
+		svc := d.prj.Services[services.AdpgName]
 
 		// TODO: helper addService to project
-		chownName := services.ChownContainer(prj, svc)
-		initAdpgServiceName := services.InitContainer(prj, svc)
+		chownName := services.ChownContainer(d.prj, svc)
+		initAdpgServiceName := services.InitContainer(d.prj, svc)
 
 		// set secrets for init-adpg container
-		for k, v := range xSecrets[services.AdpgName] {
+		for k, v := range d.xSecrets[services.AdpgName] {
 			source := services.AdpgName + "-" + k
 			s := helpers.Secret{
 				Source:     source,
@@ -313,7 +446,7 @@ func applyProject(cmd *cobra.Command, _ []string) {
 		if len(pgInit.DB) > 0 || len(pgInit.Role) > 0 {
 			initJson, err := json.Marshal(pgInit)
 			if err != nil {
-				logger.Fatal(err)
+				return err
 			}
 
 			secret := helpers.Secret{
@@ -329,7 +462,7 @@ func applyProject(cmd *cobra.Command, _ []string) {
 		}
 	}
 
-	for name, svc := range prj.Services {
+	for name, svc := range d.prj.Services {
 		servicesModHelpers = append(servicesModHelpers,
 			helpers.Platform(name, compose.DefaultPlatform),
 			helpers.CustomLabels(name, map[string]string{compose.ADLabel: ""}),
@@ -337,11 +470,77 @@ func applyProject(cmd *cobra.Command, _ []string) {
 		)
 	}
 
-	if err = servicesModHelpers.Apply(prj); err != nil {
-		logger.Fatal(err)
+	if err = servicesModHelpers.Apply(d.prj); err != nil {
+		return err
+	}
+
+	d.jobs = services.ExtractJobs(d.prj)
+
+	return compose.SetFieldsLabel(d.prj, d.macKey)
+}
+
+// logUnsealData prints the encrypted unseal data and key shares which could
+// not be saved, they are lost otherwise.
+func logUnsealData(unsealData any, unMappedData map[string]any) {
+	log.Warnf("unseal data: %v", unsealData)
+
+	var names []string
+	for k := range unMappedData {
+		if strings.HasPrefix(k, services.VaultUnsealShare+"-") {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		log.Warnf("%s: %v", k, unMappedData[k])
+	}
+}
+
+// vaultConfigWithCredentials sets the database credentials kept in un-mapped
+// to the connection url of the postgresql storage. The other storages are
+// returned as is.
+func vaultConfigWithCredentials(config string, unMap map[string]string) (string, error) {
+	var configFile services.VaultConfigFile
+	if err := json.Unmarshal([]byte(config), &configFile); err != nil {
+		return "", err
+	}
+	if configFile.Storage.Postgresql == nil {
+		return config, nil
+	}
+
+	u, err := url.Parse(configFile.Storage.Postgresql.ConnectionUrl)
+	if err != nil {
+		return "", err
+	}
+
+	u.Path = unMap[services.PgDbName]
+	u.User = url.UserPassword(unMap[services.PgDbUser], unMap[services.PgDbPass])
+
+	configFile.Storage.Postgresql.ConnectionUrl = u.String()
+	b, err := json.Marshal(configFile)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func applyProject(cmd *cobra.Command, _ []string) {
+	logger := log.WithField("command", "apply")
+
+	dryRunMode := getBool(cmd, "dry-run")
+	debugMode := getBool(cmd, "debug")
+	force := getBool(cmd, "force")
+	planFile, _ := cmd.Flags().GetString("plan-file")
+
+	if getBool(cmd, "plan") {
+		showPlan(cmd, logger)
+		return
 	}
 
-	services.PauseContainer(prj)
+	d, err := newDeployment(cmd, !dryRunMode)
+	if err != nil {
+		logger.Fatal(err)
+	}
 
 	if dryRunMode {
 		closer, err := setOutput(cmd)
@@ -354,53 +553,63 @@ func applyProject(cmd *cobra.Command, _ []string) {
 		defer func() { _ = enc.Close() }()
 
 		enc.SetIndent(2)
-		_ = enc.Encode(prj)
-		_ = enc.Encode(prj.Environment)
+		_ = enc.Encode(d.prj)
+		_ = enc.Encode(d.prj.Environment)
 		return
 	}
 
-	initPrj, err := prj.WithProfiles([]string{services.InitContainerProfile})
-	if err != nil {
+	if len(planFile) > 0 {
+		if err = d.checkPlan(cmd.Context(), planFile); err != nil {
+			logger.Fatal(err)
+		}
+		d.removeOrphans = true
+	}
+
+	if err = d.up(cmd.Context(), debugMode, force); err != nil {
 		logger.Fatal(err)
 	}
+}
 
-	defer func() {
-		if err != nil {
-			logger.Fatal(err)
-		}
-	}()
+// runJobs runs the init jobs, their containers are kept if keep is set.
+func (d *deployment) runJobs(ctx context.Context, keep bool) error {
+	r := services.NewJobRunner(d.comp, d.prj, d.jobs)
+	r.MacKey = d.macKey
+	r.Retries = d.jobRetries
+	r.Timeout = d.timeout()
+	r.Keep = keep
+	return r.Run(ctx)
+}
 
-	if len(initPrj.Services) > 0 {
-		if err := assets.LoadBusyboxImage(cmd.Context()); err != nil {
-			logger.Fatal(err)
-		}
-		if err := comp.Up(cmd.Context(), initPrj, true); err != nil {
-			logger.Fatal(err)
-		}
+func (d *deployment) up(ctx context.Context, debug, force bool) (err error) {
+	if err = d.runJobs(ctx, debug); err != nil {
+		return err
+	}
 
-		defer func() {
-			if !debugMode {
-				err := comp.Remove(cmd.Context(), initPrj, initPrj.ServiceNames()...)
-				if err != nil {
-					log.Warnf("Removing init containers failed: %v", err)
-				}
-			}
-		}()
+	stop := d.waitProgress(ctx)
+	defer stop()
+
+	plan, err := d.plan(ctx)
+	if err != nil {
+		return err
+	}
+	rolledOut, err := d.rollout(ctx, plan)
+	if err != nil {
+		return err
 	}
 
-	eg, _ := errgroup.WithContext(cmd.Context())
-	if _, ok := prj.Services[services.VaultName]; ok {
+	eg, _ := errgroup.WithContext(ctx)
+	if len(vaultServices(d.prj)) > 0 {
 		eg.Go(func() error {
-			return vaultInit(cmd.Context(), prj, comp, aes, force)
+			return d.vaultInit(ctx, force)
 		})
 	}
 
-	containerName := getContainerNameIfItIsRunning(cmd.Context(), comp, prj.Name)
-	if force && len(containerName) > 0 {
-		time.Sleep(5 * time.Second)
+	opts := d.upOptions()
+	if d.removeOrphans {
+		opts = append(opts, compose.WithRemoveOrphans())
 	}
-
-	err = comp.Up(cmd.Context(), prj, true)
+	opts = append(opts, compose.WithForceRecreate(excluded(plan.Forced(), rolledOut)...))
+	err = d.comp.Up(ctx, d.prj, true, opts...)
 
 	if e := eg.Wait(); e != nil {
 		if err == nil {
@@ -409,22 +618,129 @@ func applyProject(cmd *cobra.Command, _ []string) {
 			err = fmt.Errorf("%v: %v", err, e)
 		}
 	}
+
+	return err
+}
+
+// timeout returns how long to wait for the services.
+func (d *deployment) timeout() time.Duration {
+	if d.waitTimeout <= 0 {
+		return defaultWaitTimeout
+	}
+	return d.waitTimeout
+}
+
+func (d *deployment) upOptions() []compose.UpOption {
+	return []compose.UpOption{compose.WithWaitTimeout(d.timeout())}
+}
+
+// waitProgress logs the state of the ADCM containers which are not healthy
+// yet until stop is called.
+func (d *deployment) waitProgress(ctx context.Context) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		start := time.Now()
+		tik := time.NewTicker(progressInterval)
+		defer tik.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-tik.C:
+			}
+
+			containers, err := d.comp.ProjectContainers(ctx, d.prj.Name)
+			if err != nil {
+				continue
+			}
+			for _, c := range containers {
+				if c.Labels[compose.ADAppTypeLabelKey] != services.AdcmName || strings.Contains(c.Status, "(healthy)") {
+					continue
+				}
+				log.Infof("Waiting for %s: %s (%s)", c.Labels[api.ServiceLabel], c.Status,
+					time.Since(start).Round(time.Second))
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// rollout recreates the changed ADCM instances batch by batch before the rest
+// of the project is brought up, so the other instances keep serving. It
+// returns the recreated instances.
+func (d *deployment) rollout(ctx context.Context, plan *compose.Plan) ([]string, error) {
+	var names, forced []string
+	for _, s := range plan.Services {
+		if s.Action != compose.ActionRecreate ||
+			d.prj.Services[s.Service].Labels[compose.ADAppTypeLabelKey] != services.AdcmName {
+			continue
+		}
+		names = append(names, s.Service)
+		if s.Force {
+			forced = append(forced, s.Service)
+		}
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	opts := append(d.upOptions(), compose.WithForceRecreate(forced...))
+	return names, d.comp.Rollout(ctx, d.prj, names, d.maxUnavailable, opts...)
+}
+
+// excluded returns the names which are not in exclude.
+func excluded(names, exclude []string) []string {
+	var out []string
+	for _, name := range names {
+		if !slices.Contains(exclude, name) {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// vaultServices returns the sorted names of the Vault nodes. The first node
+// initializes Vault and keeps the unseal data.
+func vaultServices(prj *composeTypes.Project) []string {
+	var names []string
+	for name, svc := range prj.Services {
+		if svc.Labels[compose.ADAppTypeLabelKey] == services.VaultName {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
 }
 
-func getContainerNameIfItIsRunning(ctx context.Context, comp *compose.Compose, prjName string) string {
+// runningVaultContainers returns the names of the running Vault containers by
+// service name.
+func runningVaultContainers(ctx context.Context, comp *compose.Compose, prjName string) map[string]string {
+	containers := map[string]string{}
 	lst, _ := comp.List(ctx, false)
 	for _, l := range lst {
 		lbl := l.Labels
 		if lbl[api.ProjectLabel] == prjName &&
-			lbl[api.ServiceLabel] == services.VaultName &&
+			lbl[compose.ADAppTypeLabelKey] == services.VaultName &&
 			l.State == "running" {
-			return strings.Trim(l.Names[0], "/")
+			containers[lbl[api.ServiceLabel]] = strings.Trim(l.Names[0], "/")
 		}
 	}
-	return ""
+	return containers
 }
 
-func vaultInit(ctx context.Context, prj *composeTypes.Project, comp *compose.Compose, aes secrets.Secrets, force bool) error {
+// vaultInit initializes Vault through the first node, if needed, and unseals
+// every sealed node.
+func (d *deployment) vaultInit(ctx context.Context, force bool) error {
+	prj, aes := d.prj, d.aes
 	output := prj.ComposeFiles[0]
 	var adcmYaml map[string]any
 	b, err := os.ReadFile(output)
@@ -435,48 +751,52 @@ func vaultInit(ctx context.Context, prj *composeTypes.Project, comp *compose.Com
 		return err
 	}
 
-	var containerName string
-	var count int
-	tik := time.NewTicker(2 * time.Second)
-
-OUT:
-	for {
-		select {
-		case <-tik.C:
-			if count == 15 {
-				tik.Stop()
-				return fmt.Errorf("vault init timed out")
-			}
-
-			count++
-			containerName = getContainerNameIfItIsRunning(ctx, comp, prj.Name)
-			if len(containerName) > 0 {
-				tik.Stop()
-				break OUT
-			}
-		}
-	}
+	nodes := vaultServices(prj)
+	primary := nodes[0]
 
-	unsealRunner, err := image.New(containerName)
+	// the containers of the previous configuration are going to be
+	// recreated, the wait is for the new ones
+	states, err := d.comp.WaitForRunning(ctx, prj, nodes, d.timeout())
 	if err != nil {
-		return err
+		return fmt.Errorf("vault init: %v", err)
 	}
 
-	status, err := unsealRunner.Status(ctx)
-	if err != nil {
-		return fmt.Errorf("read vault status failed: %v", err)
+	runners := make(map[string]unseal.Runner, len(nodes))
+	var sealed []string
+	var primaryStatus *unseal.SealStatusResponse
+	for _, node := range nodes {
+		r, err := d.vaultRunner(node, states[node].Container)
+		if err != nil {
+			return err
+		}
+		runners[node] = r
+
+		status, err := vaultReadStatus(ctx, r)
+		if err != nil {
+			return fmt.Errorf("read vault status of %s failed: %v", node, err)
+		}
+		if node == primary {
+			primaryStatus = status
+		}
+		if status.Sealed {
+			sealed = append(sealed, node)
+		}
 	}
 
-	if !status.Sealed {
+	if len(sealed) == 0 {
 		return nil
 	}
 
+	// an auto-unsealed Vault is initialized with recovery keys and unseals
+	// itself
+	autoUnseal := primaryStatus.Type != unseal.SealTypeShamir
+
 	var unsealDataRaw string
-	unMappedData := get(adcmYaml, []string{"services", services.VaultName, "x-secrets", "un-mapped"})
+	unMappedData := get(adcmYaml, []string{"services", primary, "x-secrets", "un-mapped"})
 	unsealDataEnc, unsealDataIsExists := unMappedData[services.VaultUnsealData]
 	if unsealDataIsExists {
 		if aes != nil {
-			if unsealDataRaw, err = aes.DecryptValue(unsealDataEnc.(string)); err != nil {
+			if unsealDataRaw, err = aes.DecryptValue(unsealDataEnc.(string), primary, services.VaultUnsealData); err != nil {
 				return fmt.Errorf("decrypt vault init data failed: %v", err)
 			}
 		} else {
@@ -484,23 +804,61 @@ OUT:
 		}
 	}
 
+	params := vaultInitParams(prj)
+
 	var unsealData *unseal.VaultInitData
-	if !status.Initialized {
+	var keys []string
+	if !primaryStatus.Initialized {
 		if unsealDataIsExists && !force {
 			return fmt.Errorf("you are trying unseal Vault/Openbao with uninitialized data. "+
 				"Remove the services.%s.x-secrets.un-mapped.%s key mannualy before call apply command. "+
 				"Or rerun the command with --force flag, then unseal data will be overwritten",
-				services.VaultName, services.VaultUnsealData)
+				primary, services.VaultUnsealData)
+		}
+
+		opts := unseal.InitOptions{
+			SecretShares:    params.KeyShares,
+			SecretThreshold: params.KeyThreshold,
+			Recovery:        autoUnseal,
+		}
+		if unsealData, err = runners[primary].Init(ctx, opts); err != nil {
+			return err
+		}
+		keys = unsealData.UnsealKeysB64
+		if autoUnseal {
+			keys = unsealData.RecoveryKeysB64
+		}
+
+		for k := range unMappedData {
+			if strings.HasPrefix(k, services.VaultUnsealShare+"-") {
+				delete(unMappedData, k)
+			}
+		}
+
+		// the unseal keys are kept by the share holders only
+		if len(params.KeyRecipients) > 0 {
+			shares, err := encryptKeyShares(aes, primary, keys, params.KeyRecipients)
+			if err != nil {
+				return err
+			}
+			for k, v := range shares {
+				unMappedData[k] = v
+			}
+			unsealData.UnsealKeysB64 = nil
+			unsealData.UnsealKeysHex = nil
+			unsealData.RecoveryKeysB64 = nil
+			unsealData.RecoveryKeysHex = nil
 		}
 
-		ud, err := unsealRunner.RawInitData(ctx)
+		ud, err := json.Marshal(unsealData)
 		if err != nil {
 			return err
 		}
 		unsealDataRaw = string(ud)
+		unsealDataEnc = unsealDataRaw
 
 		if aes != nil {
-			if unsealDataEnc, err = aes.EncryptValue(unsealDataRaw); err != nil {
+			if unsealDataEnc, err = aes.EncryptValue(unsealDataRaw, primary, services.VaultUnsealData); err != nil {
 				// this shouldn't happen, but https://go.dev/issue/66821
 				return fmt.Errorf("encrypt vault init data failed: %v", err)
 			}
@@ -514,31 +872,124 @@ OUT:
 
 		if err = enc.Encode(adcmYaml); err != nil {
 			// this shouldn't happen, but if it does, print the unseal data
-			log.Warnf("unseal data: %s", unsealDataEnc)
+			logUnsealData(unsealDataEnc, unMappedData)
 			return fmt.Errorf("marshal compose file failed: %v", err)
 		}
 
-		if err = os.WriteFile(output, buf.Bytes(), 0600); err != nil {
+		// the file keeps the only copies of the unseal key shares
+		if err = utils.WriteFileAtomic(output, buf.Bytes(), 0640); err != nil {
+			logUnsealData(unsealDataEnc, unMappedData)
 			return fmt.Errorf("write vault init data to adcm.yaml file failed: %v", err)
 		}
+	} else if !autoUnseal {
+		if keys, err = vaultUnsealKeys(aes, primary, unsealDataRaw, unMappedData, d.unsealIdentities, params); err != nil {
+			return err
+		}
 	}
 
-	if err = json.Unmarshal([]byte(unsealDataRaw), &unsealData); err != nil {
-		return fmt.Errorf("unmarshal unseal data failed: %v", err)
+	if autoUnseal {
+		if err = vaultWaitUnsealed(ctx, runners, sealed, d.timeout()); err != nil {
+			return fmt.Errorf("%v, check the %s seal", err, primaryStatus.Type)
+		}
+		log.Infof("Vault is unsealed by the %s seal", primaryStatus.Type)
+		return nil
 	}
 
-	if err = unsealRunner.Unseal(ctx, unsealData.UnsealKeysB64); err != nil {
-		return err
+	for _, node := range sealed {
+		if err = runners[node].Unseal(ctx, keys); err != nil {
+			return fmt.Errorf("%s: %v", node, err)
+		}
 	}
 
 	return nil
 }
 
+// vaultReadStatus reads the seal status of the node. A container is running
+// before the Vault API listens, the status is read again until it answers.
+func vaultReadStatus(ctx context.Context, r unseal.Runner) (*unseal.SealStatusResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, vaultAPITimeout)
+	defer cancel()
+
+	for {
+		status, err := r.Status(ctx)
+		if err == nil {
+			return status, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// vaultWaitUnsealed waits until the auto-unseal seal has unsealed the nodes.
+func vaultWaitUnsealed(ctx context.Context, runners map[string]unseal.Runner, nodes []string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	pending := nodes
+	for {
+		var sealed []string
+		var lastErr error
+		for _, node := range pending {
+			status, err := runners[node].Status(ctx)
+			if err != nil {
+				lastErr = fmt.Errorf("%s: %v", node, err)
+			}
+			if err != nil || status.Sealed {
+				sealed = append(sealed, node)
+			}
+		}
+		if len(sealed) == 0 {
+			return nil
+		}
+		pending = sealed
+
+		select {
+		case <-ctx.Done():
+			err := fmt.Errorf("vault %s still sealed after %s", strings.Join(sealed, ", "), timeout)
+			if lastErr != nil {
+				err = fmt.Errorf("%v: %v", err, lastErr)
+			}
+			return err
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// vaultUnsealKeys returns the unseal keys kept in the unseal data. If the keys
+// are kept by the share holders, their shares are decrypted with the
+// identities.
+func vaultUnsealKeys(aes secrets.Secrets, primary, unsealDataRaw string, unMappedData map[string]any,
+	identities []*secrets.AgeCrypt, params services.VaultInit) ([]string, error) {
+	var unsealData *unseal.VaultInitData
+	if err := json.Unmarshal([]byte(unsealDataRaw), &unsealData); err != nil {
+		return nil, fmt.Errorf("unmarshal unseal data failed: %v", err)
+	}
+
+	if len(unsealData.UnsealKeysB64) > 0 {
+		return unsealData.UnsealKeysB64, nil
+	}
+
+	threshold := unsealData.UnsealThreshold
+	if threshold == 0 {
+		threshold = params.KeyThreshold
+	}
+	return decryptKeyShares(aes, primary, unMappedData, identities, threshold)
+}
+
+// get returns the nested map by the key path, the missing maps are created.
 func get(m map[string]any, key []string) map[string]any {
 	x := m
 	for _, k := range key {
-		v := x[k]
-		x = v.(map[string]any)
+		v, ok := x[k].(map[string]any)
+		if !ok {
+			v = map[string]any{}
+			x[k] = v
+		}
+		x = v
 	}
 	return x
 }
@@ -0,0 +1,235 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/arenadata/adcm-installer/assets"
+	"github.com/arenadata/adcm-installer/internal/services"
+	"github.com/arenadata/adcm-installer/pkg/backup"
+	"github.com/arenadata/adcm-installer/pkg/compose"
+	"github.com/arenadata/adcm-installer/pkg/utils"
+
+	composeTypes "github.com/compose-spec/compose-go/v2/types"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const (
+	backupConfigFile = "adcm.yaml"
+	backupAdpgDump   = "adpg.sql"
+	backupAdpgUser   = "postgres"
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Back up an installation to an archive",
+	Long: `Creates a single archive with everything needed to recreate the installation:
+the configuration file with encrypted secrets, the content of the service
+volumes (ADCM data, Consul storage, bind mounted directories), a dump of the
+managed ADPG cluster taken with pg_dumpall and a manifest with the images and
+checksums of the archived files. Vault storage is saved as a part of the ADPG
+dump or of the Vault data volume. The installation must be running.
+- --age-key takes the value of the private key in clear text. Has priority over
+            --age-key-file
+- --age-key-file takes the value of the path to the file with the private key
+- --file specifies the path to the configuration file
+- --output specifies the path of the archive, <name>-<timestamp>.tar.gz by
+           default`,
+	Run: backupProject,
+}
+
+func init() {
+	rootCmd.AddCommand(backupCmd)
+
+	ageKeyFlags(backupCmd, "age-key", ageKeyFileName)
+	configFileFlags(backupCmd)
+	backupCmd.Flags().StringP("output", "o", "", "Archive output filename")
+}
+
+func backupProject(cmd *cobra.Command, _ []string) {
+	logger := log.WithField("command", "backup")
+	ctx := cmd.Context()
+
+	configFilePath, _ := cmd.Flags().GetString("file")
+	prj, err := readConfigFile(configFilePath)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	aes, err := encoder(cmd, prj)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	xSecrets, _, err := secretsDecrypt(prj.Services, aes)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	comp, err := compose.NewComposeService()
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	arc, err := newBackup(ctx, comp, prj, xSecrets)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	defer func() { _ = arc.Close() }()
+
+	outputPath, _ := cmd.Flags().GetString("output")
+	if len(outputPath) == 0 {
+		outputPath = fmt.Sprintf("%s-%s.tar.gz", prj.Name, arc.Manifest.Created.Format("20060102150405"))
+	}
+
+	if err = writeBackup(arc, outputPath); err != nil {
+		logger.Fatal(err)
+	}
+
+	logger.Infof("Backup saved to %s", outputPath)
+}
+
+// newBackup stages the configuration file, the ADPG dump and the volumes of
+// the running project.
+func newBackup(ctx context.Context, comp *compose.Compose, prj *composeTypes.Project, xSecrets map[string]map[string]string) (_ *backup.Archive, err error) {
+	if err = assets.LoadBusyboxImage(ctx); err != nil {
+		return nil, err
+	}
+
+	arc, err := backup.New(prj.Name, version)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = arc.Close()
+		}
+	}()
+
+	if err = backupFile(arc, backupConfigFile, prj.ComposeFiles[0]); err != nil {
+		return nil, err
+	}
+
+	for _, name := range prj.ServiceNames() {
+		svc := prj.Services[name]
+
+		img := backup.Image{Image: svc.Image}
+		if img.Digest, err = comp.ImageDigest(ctx, svc.Image); err != nil {
+			log.Warnf("Image %s: %v", svc.Image, err)
+		}
+		arc.Manifest.Images[name] = img
+	}
+
+	if _, ok := prj.Services[services.AdpgName]; ok {
+		log.Info("Dumping ADPG cluster ...")
+		env := []string{"PGPASSWORD=" + xSecrets[services.AdpgName]["password"]}
+		dumpCmd := []string{"pg_dumpall", "--clean", "--if-exists", "-U", backupAdpgUser}
+		err = backupStream(arc, backupAdpgDump, func(w io.Writer) error {
+			return comp.ExecStream(ctx, prj.Name+"-"+services.AdpgName, env, dumpCmd, nil, w)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("ADPG dump: %v", err)
+		}
+	}
+
+	for _, v := range backupVolumes(prj) {
+		log.Infof("Archiving %s volume %s ...", v.Service, v.Source)
+		vol := compose.Volume{Type: v.Type, Source: v.Source}
+		err = backupStream(arc, v.File, func(w io.Writer) error {
+			return comp.VolumeExport(ctx, assets.ImageName, vol, w)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("volume %s: %v", v.Source, err)
+		}
+		arc.Manifest.Volumes = append(arc.Manifest.Volumes, v)
+	}
+
+	return arc, nil
+}
+
+// backupVolumes lists the volumes to archive. The ADPG data volume is skipped,
+// its content is saved with pg_dumpall.
+func backupVolumes(prj *composeTypes.Project) []backup.Volume {
+	var out []backup.Volume
+	for _, name := range prj.ServiceNames() {
+		svc := prj.Services[name]
+		for i, mnt := range svc.Volumes {
+			if name == services.AdpgName && mnt.Target == services.ADPGDataMountPath {
+				continue
+			}
+
+			v := backup.Volume{
+				Service: name,
+				Type:    mnt.Type,
+				Source:  mnt.Source,
+				File:    fmt.Sprintf("volumes/%s-%d.tar", name, i),
+			}
+
+			switch mnt.Type {
+			case composeTypes.VolumeTypeVolume:
+				v.Key = mnt.Source
+				if vol, ok := prj.Volumes[mnt.Source]; ok && len(vol.Name) > 0 {
+					v.Source = vol.Name
+				}
+			case composeTypes.VolumeTypeBind:
+			default:
+				continue
+			}
+
+			out = append(out, v)
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].File < out[j].File })
+	return out
+}
+
+func backupFile(arc *backup.Archive, name, src string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	return backupStream(arc, name, func(w io.Writer) error {
+		_, err := io.Copy(w, f)
+		return err
+	})
+}
+
+func backupStream(arc *backup.Archive, name string, fn func(io.Writer) error) error {
+	w, err := arc.Create(name)
+	if err != nil {
+		return err
+	}
+
+	if err = fn(w); err != nil {
+		_ = w.Close()
+		return err
+	}
+
+	return w.Close()
+}
+
+func writeBackup(arc *backup.Archive, outputPath string) error {
+	return utils.WriteAtomic(outputPath, 0600, arc.Write)
+}
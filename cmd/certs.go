@@ -0,0 +1,120 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/arenadata/adcm-installer/internal/services"
+	"github.com/arenadata/adcm-installer/pkg/certs"
+	"github.com/arenadata/adcm-installer/pkg/secrets"
+
+	composeTypes "github.com/compose-spec/compose-go/v2/types"
+	"github.com/spf13/cobra"
+)
+
+// caSecretsScope is the service name the values of the project x-secrets are
+// encrypted with.
+const caSecretsScope = ""
+
+// macKeySecret is the project x-secrets key of the key for fingerprinting
+// secrets.
+const macKeySecret = "mac-key"
+
+var certsCmd = &cobra.Command{
+	Use:   "certs",
+	Short: "Manage TLS certificates",
+	Long: `Shows and renews the certificates stored in x-secrets. Certificates issued by
+the project CA (adi init --tls=auto) can be renewed.`,
+}
+
+// certKeyPairs maps the x-secrets keys of certificates to their private keys.
+var certKeyPairs = map[string]string{
+	services.PemCert:      services.PemKey,
+	services.PgSslCertKey: services.PgSslKeyKey,
+}
+
+// caCopies are the x-secrets keys where the services keep the CA certificate.
+var caCopies = []string{services.PemCa, services.PgSslCaKey}
+
+func init() {
+	rootCmd.AddCommand(certsCmd)
+}
+
+// storeCA encrypts the CA into the project x-secrets.
+func storeCA(sec *services.XSecrets, ca *certs.CA, aes secrets.Secrets) error {
+	keyPEM, err := ca.KeyPEM()
+	if err != nil {
+		return err
+	}
+
+	data := map[string]string{
+		services.PemCa:    ca.CertPEM(),
+		services.PemCaKey: keyPEM,
+	}
+	if sec.Data == nil {
+		sec.Data = map[string]string{}
+	}
+	for k, v := range data {
+		if aes != nil {
+			if v, err = aes.EncryptValue(v, caSecretsScope, k); err != nil {
+				return err
+			}
+		}
+		sec.Data[k] = v
+	}
+
+	return nil
+}
+
+// readCA returns the project CA or nil if the project has none.
+func readCA(prj *composeTypes.Project, aes secrets.Secrets) (*certs.CA, error) {
+	ext, ok := prj.Extensions[services.XSecretsKey]
+	if !ok {
+		return nil, nil
+	}
+
+	data, err := decrypt(aes, caSecretsScope, ext.(*services.XSecrets).Data)
+	if err != nil {
+		return nil, err
+	}
+	if len(data[services.PemCa]) == 0 || len(data[services.PemCaKey]) == 0 {
+		return nil, nil
+	}
+
+	ca, err := certs.ParseCA(data[services.PemCa], data[services.PemCaKey])
+	if err != nil {
+		return nil, fmt.Errorf("project CA: %v", err)
+	}
+	return ca, nil
+}
+
+// reEncryptCA re-encrypts the project CA with the new master key.
+func reEncryptCA(sec *services.XSecrets, oldKey, newKey []byte) error {
+	dec, err := secrets.NewAesCrypt(oldKey)
+	if err != nil {
+		return err
+	}
+	enc, err := secrets.NewAesCrypt(newKey)
+	if err != nil {
+		return err
+	}
+
+	if err = reEncrypt(caSecretsScope, sec.Data, dec, enc); err != nil {
+		return fmt.Errorf("project CA: %v", err)
+	}
+	return nil
+}
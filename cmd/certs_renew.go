@@ -0,0 +1,166 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cmd
+
+import (
+	"github.com/arenadata/adcm-installer/internal/services"
+	"github.com/arenadata/adcm-installer/pkg/certs"
+	"github.com/arenadata/adcm-installer/pkg/secrets"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var certsRenewCmd = &cobra.Command{
+	Use:   "renew",
+	Short: "Renew certificates before they expire",
+	Long: `Reissues the certificates of the project CA which expire within the --before
+period with the same subject and names and a new private key. The CA
+certificate itself is renewed with the same key, so the certificates issued
+before stay valid. Certificates not issued by the project CA are not changed.
+The configuration file is rewritten atomically, run adi apply afterwards to
+recreate the services with the new certificates.
+- --age-key takes the value of the private key in clear text. Has priority over
+            --age-key-file
+- --age-key-file takes the value of the path to the file with the private key
+- --before specifies the period before the expiration in which a certificate
+           is renewed, 720h by default
+- --file specifies the path to the configuration file
+- --force renews all certificates of the project CA regardless of expiry`,
+	Run: certsRenew,
+}
+
+func init() {
+	certsCmd.AddCommand(certsRenewCmd)
+
+	ageKeyFlags(certsRenewCmd, "age-key", ageKeyFileName)
+	configFileFlags(certsRenewCmd)
+	certsRenewCmd.Flags().Duration("before", certs.RenewBefore, "Period before the expiration to renew certificates")
+	certsRenewCmd.Flags().Bool("force", false, "Renew all certificates")
+}
+
+func setSecretValue(xsec *services.XSecrets, aes secrets.Secrets, svcName, key, value string) error {
+	if aes != nil {
+		var err error
+		if value, err = aes.EncryptValue(value, svcName, key); err != nil {
+			return err
+		}
+	}
+	xsec.Data[key] = value
+	return nil
+}
+
+func certsRenew(cmd *cobra.Command, _ []string) {
+	logger := log.WithField("command", "certs-renew")
+
+	configFilePath, _ := cmd.Flags().GetString("file")
+	prj, err := readConfigFile(configFilePath)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if len(configFilePath) == 0 {
+		configFilePath = prj.ComposeFiles[0]
+	}
+
+	aes, err := encoder(cmd, prj)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	ca, err := readCA(prj, aes)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if ca == nil {
+		logger.Fatal("Project has no CA, it is created by adi init --tls=auto")
+	}
+
+	xSecrets, _, err := secretsDecrypt(prj.Services, aes)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	before, _ := cmd.Flags().GetDuration("before")
+	force := getBool(cmd, "force")
+
+	var renewed int
+	var oldCaPEM string
+	if certs.ExpiresWithin(ca.Cert, before) {
+		oldCaPEM = ca.CertPEM()
+		if ca, err = ca.Renew(); err != nil {
+			logger.Fatal(err)
+		}
+		if err = storeCA(prj.Extensions[services.XSecretsKey].(*services.XSecrets), ca, aes); err != nil {
+			logger.Fatal(err)
+		}
+		logger.Info("Project CA renewed")
+		renewed++
+	}
+
+	for _, name := range prj.ServiceNames() {
+		ext, ok := prj.Services[name].Extensions[services.XSecretsKey]
+		if !ok {
+			continue
+		}
+		xsec := ext.(*services.XSecrets)
+		data := xSecrets[name]
+
+		if len(oldCaPEM) > 0 {
+			for _, k := range caCopies {
+				if data[k] != oldCaPEM {
+					continue
+				}
+				if err = setSecretValue(xsec, aes, name, k, ca.CertPEM()); err != nil {
+					logger.Fatal(err)
+				}
+			}
+		}
+
+		for certKey, keyKey := range certKeyPairs {
+			cert, err := certs.ParseCertificate(data[certKey])
+			if err != nil || !ca.Issued(cert) {
+				continue
+			}
+			if !force && !certs.ExpiresWithin(cert, before) {
+				continue
+			}
+
+			certPEM, keyPEM, err := ca.Reissue(data[certKey])
+			if err != nil {
+				logger.Fatalf("%s: %s: %v", name, certKey, err)
+			}
+			if err = setSecretValue(xsec, aes, name, certKey, certPEM); err != nil {
+				logger.Fatal(err)
+			}
+			if err = setSecretValue(xsec, aes, name, keyKey, keyPEM); err != nil {
+				logger.Fatal(err)
+			}
+			logger.Infof("%s: %s renewed", name, certKey)
+			renewed++
+		}
+	}
+
+	if renewed == 0 {
+		logger.Info("No certificates to renew")
+		return
+	}
+
+	if err = writeConfigFile(configFilePath, prj); err != nil {
+		logger.Fatal(err)
+	}
+
+	logger.Infof("%d certificates renewed, run adi apply to recreate the services", renewed)
+}
@@ -0,0 +1,120 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cmd
+
+import (
+	"crypto/x509"
+	"fmt"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/arenadata/adcm-installer/pkg/certs"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var certsShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show certificates and their expiry",
+	Long: `Displays the certificates stored in x-secrets of the project and its services
+with their expiration date. Certificates expiring within the --before period
+are reported as due for renewal.
+- --age-key takes the value of the private key in clear text. Has priority over
+            --age-key-file
+- --age-key-file takes the value of the path to the file with the private key
+- --before specifies the period before the expiration in which a certificate
+           is due for renewal, 720h by default
+- --file specifies the path to the configuration file`,
+	Run: certsShow,
+}
+
+func init() {
+	certsCmd.AddCommand(certsShowCmd)
+
+	ageKeyFlags(certsShowCmd, "age-key", ageKeyFileName)
+	configFileFlags(certsShowCmd)
+	certsShowCmd.Flags().Duration("before", certs.RenewBefore, "Period before the expiration to renew certificates")
+}
+
+func certStatus(cert *x509.Certificate, before time.Duration) string {
+	left := time.Until(cert.NotAfter)
+	switch {
+	case left <= 0:
+		return "expired"
+	case left < before:
+		return fmt.Sprintf("renewal due (%d days)", int(left.Hours()/24))
+	}
+	return fmt.Sprintf("valid (%d days)", int(left.Hours()/24))
+}
+
+func certsShow(cmd *cobra.Command, _ []string) {
+	logger := log.WithField("command", "certs-show")
+
+	configFilePath, _ := cmd.Flags().GetString("file")
+	prj, err := readConfigFile(configFilePath)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	aes, err := encoder(cmd, prj)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	ca, err := readCA(prj, aes)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	xSecrets, _, err := secretsDecrypt(prj.Services, aes)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	before, _ := cmd.Flags().GetDuration("before")
+	tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "SERVICE\tSECRET\tSUBJECT\tISSUER\tNOT AFTER\tSTATUS")
+
+	row := func(service, key string, cert *x509.Certificate) {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", service, key, cert.Subject.CommonName,
+			cert.Issuer.CommonName, cert.NotAfter.Format(time.DateOnly), certStatus(cert, before))
+	}
+
+	if ca != nil {
+		row("-", "ca.pem", ca.Cert)
+	}
+
+	for _, name := range prj.ServiceNames() {
+		data := xSecrets[name]
+		keys := make([]string, 0, len(data))
+		for k := range data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			cert, err := certs.ParseCertificate(data[k])
+			if err != nil {
+				continue
+			}
+			row(name, k, cert)
+		}
+	}
+
+	_ = tw.Flush()
+}
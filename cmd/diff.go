@@ -0,0 +1,194 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/arenadata/adcm-installer/internal/services"
+	"github.com/arenadata/adcm-installer/pkg/compose"
+	"github.com/arenadata/adcm-installer/pkg/utils"
+
+	"github.com/docker/compose/v2/pkg/api"
+	"github.com/docker/docker/api/types/container"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Show changes apply would make",
+	Long: `Compares the configuration with the running containers and displays the
+services which will be created, recreated (with the changed fields: image,
+environment, secrets, ports, etc.) or removed as orphans, and whether Vault will
+be initialized. The orphans are removed only by adi apply --plan-file. A change
+of the secrets content is not detected for containers created by adi versions
+which did not record it. Same as adi apply --plan. The plan saved with --output
+can be passed to adi apply --plan-file to make sure the reviewed changes are the
+ones which are applied.
+- --age-key takes the value of the private key in clear text. Has priority over
+            --age-key-file
+- --age-key-file takes the value of the path to the file with the private key
+- --file specifies the path to the configuration file
+- --output specifies the path of the file to which the plan will be written in
+           JSON format, - writes it to stdout instead of the text output`,
+	Run: diffProject,
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	ageKeyFlags(diffCmd, "age-key", ageKeyFileName)
+	configFileFlags(diffCmd)
+	diffCmd.Flags().StringP("output", "o", "", "Plan output filename")
+}
+
+func diffProject(cmd *cobra.Command, _ []string) {
+	showPlan(cmd, log.WithField("command", "diff"))
+}
+
+func showPlan(cmd *cobra.Command, logger *log.Entry) {
+	d, err := newDeployment(cmd, true)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	plan, err := d.plan(cmd.Context())
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	outputPath, _ := cmd.Flags().GetString("output")
+	if len(outputPath) > 0 {
+		b, err := json.MarshalIndent(plan, "", "  ")
+		if err != nil {
+			logger.Fatal(err)
+		}
+		b = append(b, '\n')
+
+		if outputPath == "-" {
+			_, _ = cmd.OutOrStdout().Write(b)
+			return
+		}
+		if err = utils.WriteFileAtomic(outputPath, b, 0640); err != nil {
+			logger.Fatal(err)
+		}
+	}
+
+	printPlan(cmd.OutOrStdout(), plan)
+}
+
+// plan compares the primary services of the deployment with the running
+// containers. Init containers run on every apply and are not a part of it.
+func (d *deployment) plan(ctx context.Context) (*compose.Plan, error) {
+	primary, err := d.prj.WithProfiles([]string{services.PrimaryContainerProfile})
+	if err != nil {
+		return nil, err
+	}
+
+	containers, err := d.comp.ProjectContainers(ctx, d.prj.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	var running []container.Summary
+	for _, c := range containers {
+		name := c.Labels[api.ServiceLabel]
+		if _, ok := primary.Services[name]; !ok {
+			if _, ok = d.prj.Services[name]; ok {
+				continue
+			}
+		}
+		running = append(running, c)
+	}
+
+	plan, err := compose.NewPlan(primary, running, d.macKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if nodes := vaultServices(d.prj); len(nodes) > 0 {
+		mode := d.prj.Services[nodes[0]].Labels[compose.ADVaultModeLabelKey]
+		plan.VaultInit = len(mode) > 0 && mode != services.VaultDeployModeDev &&
+			len(d.unMapped[nodes[0]][services.VaultUnsealData]) == 0
+	}
+
+	return plan, nil
+}
+
+// checkPlan fails if the deployment would not execute the saved plan.
+func (d *deployment) checkPlan(ctx context.Context, planFile string) error {
+	b, err := os.ReadFile(planFile)
+	if err != nil {
+		return err
+	}
+
+	var saved compose.Plan
+	if err = json.Unmarshal(b, &saved); err != nil {
+		return fmt.Errorf("%s: %v", planFile, err)
+	}
+
+	plan, err := d.plan(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !plan.Equal(&saved) {
+		buf := new(strings.Builder)
+		printPlan(buf, plan)
+		return fmt.Errorf("the configuration or the containers have changed since the plan was saved, "+
+			"the current plan is:\n%s", buf)
+	}
+
+	return nil
+}
+
+func printPlan(w io.Writer, plan *compose.Plan) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	_, _ = fmt.Fprintf(tw, "%s:\n", plan.Project)
+	for _, s := range plan.Services {
+		switch s.Action {
+		case compose.ActionCreate:
+			_, _ = fmt.Fprintf(tw, "  + %s\tcreate\n", s.Service)
+		case compose.ActionRecreate:
+			_, _ = fmt.Fprintf(tw, "  ~ %s\trecreate (%s)\n", s.Service, strings.Join(s.Fields, ", "))
+		case compose.ActionRemove:
+			_, _ = fmt.Fprintf(tw, "  - %s\tremove orphan\n", s.Service)
+		default:
+			_, _ = fmt.Fprintf(tw, "    %s\tunchanged\n", s.Service)
+		}
+	}
+	_ = tw.Flush()
+
+	if plan.VaultInit {
+		_, _ = fmt.Fprintln(w, "Vault will be initialized")
+	}
+
+	if !plan.HasChanges() {
+		_, _ = fmt.Fprintln(w, "No changes.")
+		return
+	}
+
+	_, _ = fmt.Fprintf(w, "Plan: %d to create, %d to recreate, %d to remove.\n",
+		plan.Count(compose.ActionCreate), plan.Count(compose.ActionRecreate), plan.Count(compose.ActionRemove))
+}
@@ -0,0 +1,86 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/arenadata/adcm-installer/pkg/compose"
+	"github.com/arenadata/adcm-installer/pkg/images"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var imagesLoadCmd = &cobra.Command{
+	Use:   "load [bundle]",
+	Short: "Load images from a bundle",
+	Long: `Verifies the digests of the bundle created by adi images save, loads its images
+into the local container engine and tags them with the names used in the
+configuration file, so adi apply does not need access to the registries.
+Without arguments, adi-images.tar.gz in the current directory is loaded.`,
+	PreRunE: cobra.MaximumNArgs(1),
+	Run:     imagesLoad,
+}
+
+func init() {
+	imagesCmd.AddCommand(imagesLoadCmd)
+}
+
+func imagesLoad(cmd *cobra.Command, args []string) {
+	logger := log.WithField("command", "images-load")
+	ctx := cmd.Context()
+
+	bundlePath := imagesBundleFileName
+	if len(args) > 0 {
+		bundlePath = args[0]
+	}
+
+	f, err := os.Open(bundlePath)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	bundle, err := images.Read(f)
+	_ = f.Close()
+	if err != nil {
+		logger.Fatal(err)
+	}
+	defer func() { _ = bundle.Close() }()
+
+	comp, err := compose.NewComposeService()
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	archive := bundle.Archive()
+	err = comp.ImageLoad(ctx, archive)
+	_ = archive.Close()
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	// depending on the image store the image id is the config or the
+	// manifest digest
+	for _, img := range bundle.Images {
+		if err = comp.ImageTag(ctx, img.ConfigDigest.String(), img.Ref); err != nil {
+			err = comp.ImageTag(ctx, img.ManifestDigest.String(), img.Ref)
+		}
+		if err != nil {
+			logger.Fatalf("%s: %v", img.Ref, err)
+		}
+		logger.Infof("Loaded %s", img.Ref)
+	}
+}
@@ -0,0 +1,210 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/arenadata/adcm-installer/assets"
+	"github.com/arenadata/adcm-installer/internal/services"
+	"github.com/arenadata/adcm-installer/pkg/compose"
+	"github.com/arenadata/adcm-installer/pkg/images"
+	"github.com/arenadata/adcm-installer/pkg/registry-client"
+
+	"github.com/distribution/reference"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const (
+	imagesBundleFileName = "adi-images.tar.gz"
+	defaultRegistry      = "hub.arenadata.io"
+)
+
+var imagesSaveCmd = &cobra.Command{
+	Use:   "save [image...]",
+	Short: "Download images to a bundle",
+	Long: `Downloads all images of the installation into a single compressed bundle which
+can be moved to a host without internet access and loaded with adi images load.
+The images are read from the configuration file. If there is no configuration
+file, the images adi init would use with the --adpg, --consul, --vault,
+--proxy, --adcm-count and --from-config flags are saved. Images passed as arguments and
+the busybox image used by init containers are always added. Every blob of the
+bundle is verified by its digest. Registry credentials are read from the docker
+config, including credential helpers, unless passed by flags.
+- --file specifies the path to the configuration file
+- --output specifies the path of the bundle, adi-images.tar.gz by default
+- --platform specifies the platform of the images, linux/amd64 by default
+- --registry specifies the registry the --registry-user credentials are used
+             for, hub.arenadata.io by default
+- --registry-user specifies the registry username
+- --registry-password-stdin reads the registry password from stdin`,
+	Run: imagesSave,
+}
+
+func init() {
+	imagesCmd.AddCommand(imagesSaveCmd)
+
+	configFileFlags(imagesSaveCmd)
+
+	f := imagesSaveCmd.Flags()
+	f.StringP("output", "o", imagesBundleFileName, "Bundle output filename")
+	f.String("platform", compose.DefaultPlatform, "Image platform")
+	f.Uint8("adcm-count", 1, "Set number of ADCM instances")
+	f.Bool(services.AdpgName, false, "Use managed ADPG")
+	f.Bool(services.ConsulName, false, "Use managed Consul")
+	f.Bool(services.VaultName, false, "Use managed Vault")
+	f.Bool(services.ProxyName, false, "Use managed proxy in front of ADCM")
+	f.String("from-config", "", "Read variables from config file")
+	f.String("registry", defaultRegistry, "Registry of the passed credentials")
+	registryFlags(imagesSaveCmd)
+	imagesSaveCmd.MarkFlagsMutuallyExclusive("file", "adcm-count")
+	imagesSaveCmd.MarkFlagsMutuallyExclusive("file", "from-config")
+}
+
+func parsePlatform(s string) (ocispec.Platform, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) < 2 || len(parts) > 3 {
+		return ocispec.Platform{}, fmt.Errorf("invalid platform %q, expected os/arch[/variant]", s)
+	}
+
+	p := ocispec.Platform{OS: parts[0], Architecture: parts[1]}
+	if len(parts) == 3 {
+		p.Variant = parts[2]
+	}
+	return p, nil
+}
+
+// installationImages returns the images of the configuration file or, if
+// there is none, of the project adi init would generate.
+func installationImages(cmd *cobra.Command) ([]string, error) {
+	configFilePath, _ := cmd.Flags().GetString("file")
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	var out []string
+	if len(configFilePath) > 0 || len(findFiles(fileNames, wd)) > 0 {
+		prj, err := readConfigFile(configFilePath)
+		if err != nil {
+			return nil, err
+		}
+		for _, svc := range prj.Services {
+			out = append(out, svc.Image)
+		}
+		return out, nil
+	}
+
+	configFile, _ := cmd.Flags().GetString("from-config")
+	adcmCount, _ := cmd.Flags().GetUint8("adcm-count")
+	prj, err := services.New(filepath.Base(wd),
+		services.WithAdpg(getBool(cmd, services.AdpgName)),
+		services.WithConsul(getBool(cmd, services.ConsulName)),
+		services.WithVault(getBool(cmd, services.VaultName)),
+		services.WithProxy(getBool(cmd, services.ProxyName)),
+		services.WithConfigFile(configFile),
+		services.WithAdcmCount(adcmCount),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err = prj.Build(); err != nil {
+		return nil, err
+	}
+
+	for _, svc := range prj.Services() {
+		out = append(out, svc.Image)
+	}
+	return out, nil
+}
+
+func imageRegistry(image string) string {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return ""
+	}
+	return reference.Domain(named)
+}
+
+func imagesSave(cmd *cobra.Command, args []string) {
+	logger := log.WithField("command", "images-save")
+
+	platformFlag, _ := cmd.Flags().GetString("platform")
+	platform, err := parsePlatform(platformFlag)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	refs, err := installationImages(cmd)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	refs = append(refs, assets.ImageName)
+	refs = append(refs, args...)
+	sort.Strings(refs)
+	refs = slices.Compact(refs)
+
+	cred, err := registryCredentials(cmd)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	registry, _ := cmd.Flags().GetString("registry")
+
+	bundle, err := images.NewBundle()
+	if err != nil {
+		logger.Fatal(err)
+	}
+	defer func() { _ = bundle.Close() }()
+
+	for _, ref := range refs {
+		var opts []client.RegistryOption
+		if cred != nil && imageRegistry(ref) == registry {
+			opts = append(opts, client.WihCredentials(cred))
+		}
+
+		logger.Infof("Downloading %s ...", ref)
+		if err = bundle.Add(cmd.Context(), ref, platform, opts...); err != nil {
+			logger.Fatal(err)
+		}
+	}
+
+	outputPath, _ := cmd.Flags().GetString("output")
+	tmp, err := os.CreateTemp(filepath.Dir(outputPath), "."+filepath.Base(outputPath)+".*")
+	if err != nil {
+		logger.Fatal(err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if err = bundle.Write(tmp); err != nil {
+		_ = tmp.Close()
+		logger.Fatal(err)
+	}
+	if err = tmp.Close(); err != nil {
+		logger.Fatal(err)
+	}
+	if err = os.Rename(tmp.Name(), outputPath); err != nil {
+		logger.Fatal(err)
+	}
+
+	logger.Infof("%d images saved to %s", len(bundle.Images), outputPath)
+}
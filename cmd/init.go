@@ -16,6 +16,7 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
 	"fmt"
@@ -25,6 +26,7 @@ import (
 
 	"github.com/arenadata/adcm-installer/internal/services"
 	"github.com/arenadata/adcm-installer/internal/services/helpers"
+	"github.com/arenadata/adcm-installer/pkg/certs"
 	"github.com/arenadata/adcm-installer/pkg/compose"
 	"github.com/arenadata/adcm-installer/pkg/secrets"
 	"github.com/arenadata/adcm-installer/pkg/utils"
@@ -56,10 +58,21 @@ error. The installation name must be unique within a single server.
 - --age-key takes the value of the private key in cleartext. Takes precedence
             over --age-key-file
 - --age-key-file takes the path to the file with the private key
+- --consul adds the Consul server with a persistent volume, gossip encryption
+           and ACLs enabled. A 3-node cluster is set by consul-count in the
+           --from-config file
 - --force allows you to overwrite the existing configuration file
 - --from-config path to a file in yaml format filled with variables for
                 fine-tuning the configuration without using interactive mode
-- --interactive fine-tuning each service in interactive mode`,
+- --interactive fine-tuning each service in interactive mode
+- --proxy adds the HAProxy service in front of the ADCM instances, which
+          balances across them with health checks and sticky sessions. The
+          ADCM ports, certificates and url apply to the proxy and the
+          instances are not published
+- --tls auto generates a project CA stored in x-secrets and issues the
+        certificates of ADCM, Vault and ADPG. ADCM and Vault connect to the
+        managed ADPG with client certificates in verify-full SSL mode. See
+        adi certs for the expiry and renewal of the certificates`,
 		PreRunE: cobra.ExactArgs(1),
 		Run:     initProject,
 	}
@@ -82,11 +95,13 @@ func initCmdFlags(cmd *cobra.Command) {
 
 	f.Uint8("adcm-count", 1, "Set number of ADCM instances")
 	f.Bool(services.AdpgName, false, "Use managed ADPG")
-	f.Bool(services.ConsulName, false, "Use managed Consul (Alpha)")
+	f.Bool(services.ConsulName, false, "Use managed Consul")
 	f.Bool(services.VaultName, false, "Use managed Vault")
+	f.Bool(services.ProxyName, false, "Use managed proxy in front of ADCM")
 	f.Bool("force", false, "Force overwrite existing config file")
 	f.BoolP("interactive", "i", false, "Interactive mode")
 
+	f.String("tls", services.TLSModeNone, "TLS mode: none or auto")
 	f.StringP("output", "o", "", "Output filename")
 	f.String("from-config", "", "Read variables from config file")
 	cmd.MarkFlagsMutuallyExclusive("adcm-count", "from-config", "interactive")
@@ -104,6 +119,7 @@ func initProject(cmd *cobra.Command, args []string) {
 		services.WithAdpg(getBool(cmd, services.AdpgName)),
 		services.WithConsul(getBool(cmd, services.ConsulName)),
 		services.WithVault(getBool(cmd, services.VaultName)),
+		services.WithProxy(getBool(cmd, services.ProxyName)),
 	}
 
 	configFile, _ := cmd.Flags().GetString("from-config")
@@ -114,8 +130,22 @@ func initProject(cmd *cobra.Command, args []string) {
 		services.WithAdcmCount(adcmCount),
 	)
 
+	var ca *certs.CA
+	switch tlsMode, _ := cmd.Flags().GetString("tls"); tlsMode {
+	case services.TLSModeNone:
+	case services.TLSModeAuto:
+		var err error
+		if ca, err = certs.NewCA(args[0] + " CA"); err != nil {
+			logger.Fatal(err)
+		}
+		opts = append(opts, services.WithCA(ca))
+	default:
+		logger.Fatalf("Unknown TLS mode %q", tlsMode)
+	}
+
 	var isNewAgeKey bool
 	var age *secrets.AgeCrypt
+	var aes secrets.Secrets
 	var masterKey *services.XSecrets
 	if !getBool(cmd, "no-crypt") {
 		key := make([]byte, 32)
@@ -135,18 +165,36 @@ func initProject(cmd *cobra.Command, args []string) {
 		}
 
 		masterKey = &services.XSecrets{
-			AgeRecipient: age.Recipient().String(),
+			AgeRecipient: age.Recipient(),
 			Key:          mKey,
 		}
 
-		aes, err := secrets.NewAesCrypt(key)
+		aesCrypt, err := secrets.NewAesCrypt(key)
 		if err != nil {
 			logger.Fatal(err)
 		}
+		aes = aesCrypt
+
+		macKey := make([]byte, 32)
+		if _, err = rand.Read(macKey); err != nil {
+			logger.Fatal(err)
+		}
+		if err = storeMacKey(masterKey, macKey, aes); err != nil {
+			logger.Fatal(err)
+		}
 
 		opts = append(opts, services.WithCrypt(aes))
 	}
 
+	if ca != nil {
+		if masterKey == nil {
+			masterKey = &services.XSecrets{}
+		}
+		if err := storeCA(masterKey, ca, aes); err != nil {
+			logger.Fatal(err)
+		}
+	}
+
 	prj, err := services.New(args[0], opts...)
 	if err != nil {
 		logger.Fatal(err)
@@ -196,7 +244,7 @@ func initProject(cmd *cobra.Command, args []string) {
 	}()
 
 	if isNewAgeKey {
-		if err = saveAgeKey(ageKeyFileName, age); err != nil {
+		if err = saveAgeKey(ageKeyFileName, age, ""); err != nil {
 			logger.Fatal(err)
 		}
 	}
@@ -264,9 +312,11 @@ func readConfigFile(conf string) (*composeTypes.Project, error) {
 	}
 
 	var sec *services.XSecrets
+	var vaultParams *services.VaultInit
 	projectOpts := []cli.ProjectOptionsFn{
 		cli.WithConsistency(false),
 		cli.WithExtension(services.XSecretsKey, sec),
+		cli.WithExtension(services.VaultInitKey, vaultParams),
 	}
 
 	if len(conf) > 0 {
@@ -279,3 +329,12 @@ func readConfigFile(conf string) (*composeTypes.Project, error) {
 
 	return prj, err
 }
+
+func writeConfigFile(path string, prj *composeTypes.Project) error {
+	buf := new(bytes.Buffer)
+	if err := toYaml(buf, prj); err != nil {
+		return err
+	}
+
+	return utils.WriteFileAtomic(path, buf.Bytes(), 0640)
+}
@@ -0,0 +1,129 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cmd
+
+import (
+	"sort"
+
+	"github.com/arenadata/adcm-installer/internal/services"
+	"github.com/arenadata/adcm-installer/pkg/compose"
+
+	"github.com/docker/compose/v2/cmd/formatter"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var logsCmd = &cobra.Command{
+	Use:   "logs [service...]",
+	Short: "Show the logs of the installation services",
+	Long: `Displays the logs of the containers of the installation, every line is prefixed
+with the name of its container, colored on a terminal. Without arguments, the
+logs of all the services are displayed, including the containers of the init
+jobs kept by adi apply --debug. The containers of the other init jobs are
+removed, the last lines of the log of a failed one are reported by the command
+which ran it. The secret values of x-secrets (passwords, private keys, tokens,
+unseal keys) are replaced with asterisks, the database names and users and the
+certificates are not. Without the --file flag, the current directory's
+adcm.yaml (adcm.yml/ad-app.yml/ad-app.yaml) is used.
+- --age-key takes the value of the private key in clear text. Has priority over
+            --age-key-file
+- --age-key-file takes the value of the path to the file with the private key
+- --file specifies the path to the configuration file, it has no shorthand
+         unlike the other commands
+- --follow streams the logs until interrupted, including the ones of the
+           containers started meanwhile
+- --since shows the logs since a timestamp (2025-01-02T13:23:37Z) or a relative
+          duration (42m)
+- --tail specifies the number of lines from the end of the logs of every
+         container, all by default`,
+	Run: logsProject,
+}
+
+func init() {
+	rootCmd.AddCommand(logsCmd)
+
+	ageKeyFlags(logsCmd, "age-key", ageKeyFileName)
+	// -f is follow, as in docker logs
+	logsCmd.Flags().String("file", "", "Application configuration file")
+	logsCmd.Flags().BoolP("follow", "f", false, "Follow the log output")
+	logsCmd.Flags().String("since", "", "Show the logs since a timestamp or a relative duration")
+	logsCmd.Flags().String("tail", "all", "Number of lines to show from the end of the logs")
+}
+
+func logsProject(cmd *cobra.Command, args []string) {
+	logger := log.WithField("command", "logs")
+
+	d, err := newDeployment(cmd, true)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	for _, name := range args {
+		if _, ok := d.prj.Services[name]; ok {
+			continue
+		}
+		if _, ok := d.jobs[name]; ok {
+			continue
+		}
+		logger.Fatalf("Service %s not found, one of %v is expected", name, d.serviceNames())
+	}
+
+	var opts []compose.LogsOption
+	if getBool(cmd, "follow") {
+		opts = append(opts, compose.WithFollow())
+	}
+	if since, _ := cmd.Flags().GetString("since"); len(since) > 0 {
+		opts = append(opts, compose.WithSince(since))
+	}
+	if tail, _ := cmd.Flags().GetString("tail"); len(tail) > 0 {
+		opts = append(opts, compose.WithTail(tail))
+	}
+
+	comp, err := compose.NewComposeService()
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	ctx := cmd.Context()
+	color := comp.Cli().Out().IsTerminal()
+	consumer := formatter.NewLogConsumer(ctx, cmd.OutOrStdout(), cmd.ErrOrStderr(), color, true, false)
+	consumer = compose.NewRedactingLogConsumer(consumer, d.secretValues())
+
+	if err = comp.Logs(ctx, d.prj.Name, args, consumer, opts...); err != nil {
+		logger.Fatal(err)
+	}
+}
+
+// serviceNames returns the names of the services and the init jobs.
+func (d *deployment) serviceNames() []string {
+	names := d.prj.ServiceNames()
+	for name := range d.jobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// secretValues returns the decrypted values of x-secrets which are secret.
+func (d *deployment) secretValues() []string {
+	var values []string
+	for _, m := range []map[string]map[string]string{d.xSecrets, d.unMapped} {
+		for _, data := range m {
+			values = append(values, services.SecretValues(data)...)
+		}
+	}
+	return values
+}
@@ -0,0 +1,281 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/arenadata/adcm-installer/assets"
+	"github.com/arenadata/adcm-installer/internal/services"
+	"github.com/arenadata/adcm-installer/pkg/backup"
+	"github.com/arenadata/adcm-installer/pkg/compose"
+	"github.com/arenadata/adcm-installer/pkg/utils"
+
+	composeTypes "github.com/compose-spec/compose-go/v2/types"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore <archive>",
+	Short: "Restore an installation from an archive",
+	Long: `Recreates an installation from an archive created by adi backup, on the same
+or on another host. The archive checksums are verified, the configuration file
+is written, the volumes are recreated and filled, the managed ADPG cluster is
+initialized and loaded from the dump, then all the containers are started.
+Existing configuration file and volumes are never overwritten without --force.
+- --age-key takes the value of the private key in clear text. Has priority over
+            --age-key-file
+- --age-key-file takes the value of the path to the file with the private key
+- --file specifies the path of the restored configuration file, adcm.yaml by
+         default
+- --force replaces existing configuration file and volume content, the
+          containers of the installation are removed first
+- --unseal-identity specifies the private key file of a holder of a Vault unseal
+                    key share, see adi apply --help
+- --unseal-via specifies how the Vault unseal commands are run, see adi apply
+               --help`,
+	PreRunE: cobra.ExactArgs(1),
+	Run:     restoreProject,
+}
+
+func init() {
+	rootCmd.AddCommand(restoreCmd)
+
+	ageKeyFlags(restoreCmd, "age-key", ageKeyFileName)
+	configFileFlags(restoreCmd)
+	restoreCmd.Flags().Bool("force", false, "Replace existing configuration file and volume content")
+	unsealIdentityFlags(restoreCmd)
+	unsealViaFlags(restoreCmd)
+}
+
+func restoreProject(cmd *cobra.Command, args []string) {
+	logger := log.WithField("command", "restore")
+	ctx := cmd.Context()
+	force := getBool(cmd, "force")
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		logger.Fatal(err)
+	}
+	arc, err := backup.Read(f)
+	_ = f.Close()
+	if err != nil {
+		logger.Fatal(err)
+	}
+	defer func() { _ = arc.Close() }()
+
+	configFilePath, _ := cmd.Flags().GetString("file")
+	if len(configFilePath) == 0 {
+		configFilePath = fileNames[0]
+		_ = cmd.Flags().Set("file", configFilePath)
+	}
+
+	if exists, err := utils.FileExists(configFilePath); err != nil {
+		logger.Fatal(err)
+	} else if exists && !force {
+		logger.Fatalf("%s already exists, use --force to overwrite it", configFilePath)
+	}
+
+	config, err := arc.ReadFile(backupConfigFile)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if err = utils.WriteFileAtomic(configFilePath, config, 0640); err != nil {
+		logger.Fatal(err)
+	}
+
+	d, err := newDeployment(cmd, true)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if d.prj.Name != arc.Manifest.Project {
+		logger.Warnf("Restoring backup of %s as %s", arc.Manifest.Project, d.prj.Name)
+	}
+
+	// the volumes of a running installation cannot be replaced while its
+	// containers use them
+	if force {
+		if err = d.comp.Down(ctx, d.prj, false); err != nil {
+			logger.Fatal(err)
+		}
+	}
+
+	// volumes are filled before the init jobs fix their permissions
+	if err = assets.LoadBusyboxImage(ctx); err != nil {
+		logger.Fatal(err)
+	}
+	if err = restoreVolumes(ctx, d.comp, d.prj, arc, force); err != nil {
+		logger.Fatal(err)
+	}
+
+	if err = d.runJobs(ctx, false); err != nil {
+		logger.Fatal(err)
+	}
+
+	if _, ok := arc.Manifest.Files[backupAdpgDump]; ok {
+		if err = restoreAdpg(ctx, d, arc); err != nil {
+			logger.Fatalf("ADPG restore: %v", err)
+		}
+	}
+
+	if err = d.up(ctx, false, false); err != nil {
+		logger.Fatal(err)
+	}
+
+	logger.Infof("%s restored from %s", d.prj.Name, args[0])
+}
+
+// restoreVolumes fills the project volumes from the archive. If serviceNames
+// is set, only volumes of these services are restored.
+func restoreVolumes(ctx context.Context, comp *compose.Compose, prj *composeTypes.Project, arc *backup.Archive, force bool, serviceNames ...string) error {
+	for _, v := range backupVolumes(prj) {
+		if len(serviceNames) > 0 && !slices.Contains(serviceNames, v.Service) {
+			continue
+		}
+		if _, ok := arc.Manifest.Files[v.File]; !ok {
+			log.Warnf("Volume %s of %s is not in the archive, skipping", v.Source, v.Service)
+			continue
+		}
+
+		if err := prepareVolume(ctx, comp, prj.Name, v, force); err != nil {
+			return err
+		}
+
+		log.Infof("Restoring %s volume %s ...", v.Service, v.Source)
+		r, err := arc.Open(v.File)
+		if err != nil {
+			return err
+		}
+		err = comp.VolumeImport(ctx, assets.ImageName, compose.Volume{Type: v.Type, Source: v.Source}, r)
+		_ = r.Close()
+		if err != nil {
+			return fmt.Errorf("volume %s: %v", v.Source, err)
+		}
+	}
+
+	return nil
+}
+
+func prepareVolume(ctx context.Context, comp *compose.Compose, prjName string, v backup.Volume, force bool) error {
+	if v.Type == composeTypes.VolumeTypeBind {
+		entries, err := os.ReadDir(v.Source)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if len(entries) > 0 && !force {
+			return fmt.Errorf("directory %s is not empty, use --force to overwrite it", v.Source)
+		}
+		for _, e := range entries {
+			if err = os.RemoveAll(filepath.Join(v.Source, e.Name())); err != nil {
+				return err
+			}
+		}
+		return os.MkdirAll(v.Source, 0755)
+	}
+
+	exists, err := comp.VolumeExists(ctx, v.Source)
+	if err != nil {
+		return err
+	}
+	if exists {
+		if !force {
+			return fmt.Errorf("volume %s already exists, use --force to overwrite it", v.Source)
+		}
+		if err = comp.VolumeRemove(ctx, v.Source); err != nil {
+			return err
+		}
+	}
+
+	return comp.VolumeCreate(ctx, prjName, v.Key, v.Source)
+}
+
+// startAdpg starts the initialized ADPG alone and waits for it to be healthy.
+func startAdpg(ctx context.Context, d *deployment) error {
+	if _, ok := d.prj.Services[services.AdpgName]; !ok {
+		return fmt.Errorf("service %s not found", services.AdpgName)
+	}
+
+	adpgPrj, err := d.prj.WithSelectedServices([]string{services.AdpgName})
+	if err != nil {
+		return err
+	}
+	return d.comp.Up(ctx, adpgPrj, true)
+}
+
+// restoreAdpg starts the initialized ADPG alone and loads the dump into it.
+func restoreAdpg(ctx context.Context, d *deployment, arc *backup.Archive) error {
+	if err := startAdpg(ctx, d); err != nil {
+		return err
+	}
+
+	dump, err := arc.Open(backupAdpgDump)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = dump.Close() }()
+
+	log.Info("Loading ADPG dump ...")
+	env := []string{"PGPASSWORD=" + d.xSecrets[services.AdpgName]["password"]}
+	// the dump drops and creates databases, which is not possible in a
+	// transaction, so it cannot be loaded with --single-transaction
+	loadCmd := []string{"psql", "-q", "-v", "ON_ERROR_STOP=1", "-U", backupAdpgUser, "-d", "postgres"}
+	return d.comp.ExecStream(ctx, d.prj.Name+"-"+services.AdpgName, env, loadCmd,
+		skipLines(dump, adpgRoleStatements(backupAdpgUser)...), nil)
+}
+
+// adpgRoleStatements are the statements of a pg_dumpall --clean --if-exists
+// dump on the role it is connected as. The current role can be neither
+// dropped nor created again, so they stop the load with ON_ERROR_STOP.
+func adpgRoleStatements(role string) []string {
+	return []string{
+		"DROP ROLE IF EXISTS " + role + ";",
+		"CREATE ROLE " + role + ";",
+	}
+}
+
+// skipLines returns the content of r without the lines equal to one of skip.
+func skipLines(r io.Reader, skip ...string) io.Reader {
+	pr, pw := io.Pipe()
+	go func() {
+		br := bufio.NewReader(r)
+		for {
+			line, err := br.ReadString('\n')
+			if !slices.Contains(skip, strings.TrimRight(line, "\r\n")) {
+				if _, werr := io.WriteString(pw, line); werr != nil {
+					// the reader is closed
+					return
+				}
+			}
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					err = nil
+				}
+				_ = pw.CloseWithError(err)
+				return
+			}
+		}
+	}()
+	return pr
+}
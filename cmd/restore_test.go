@@ -0,0 +1,87 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cmd
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+// pgDumpallHeader is the beginning of a pg_dumpall --clean --if-exists dump
+// taken as the postgres role.
+const pgDumpallHeader = `--
+-- PostgreSQL database cluster dump
+--
+
+SET default_transaction_read_only = off;
+
+SET client_encoding = 'UTF8';
+SET standard_conforming_strings = on;
+
+--
+-- Drop databases (except postgres and template1)
+--
+
+DROP DATABASE IF EXISTS adcm;
+
+
+--
+-- Drop roles
+--
+
+DROP ROLE IF EXISTS adcm;
+DROP ROLE IF EXISTS postgres;
+
+
+--
+-- Roles
+--
+
+CREATE ROLE adcm;
+ALTER ROLE adcm WITH NOSUPERUSER INHERIT NOCREATEROLE NOCREATEDB LOGIN NOREPLICATION NOBYPASSRLS PASSWORD 'SCRAM-SHA-256$4096:c2FsdA==$a2V5:c2VydmVy';
+CREATE ROLE postgres;
+ALTER ROLE postgres WITH SUPERUSER INHERIT CREATEROLE CREATEDB LOGIN REPLICATION BYPASSRLS;
+`
+
+func TestSkipLines(t *testing.T) {
+	b, err := io.ReadAll(skipLines(strings.NewReader(pgDumpallHeader), adpgRoleStatements(backupAdpgUser)...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(b)
+
+	for _, line := range adpgRoleStatements(backupAdpgUser) {
+		if strings.Contains(out, line) {
+			t.Errorf("%q is not skipped", line)
+		}
+	}
+
+	want := strings.NewReplacer("DROP ROLE IF EXISTS postgres;\n", "", "CREATE ROLE postgres;\n", "").Replace(pgDumpallHeader)
+	if out != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out, want)
+	}
+}
+
+func TestSkipLinesNoTrailingNewline(t *testing.T) {
+	b, err := io.ReadAll(skipLines(strings.NewReader("CREATE ROLE adcm;\r\nCREATE ROLE postgres;"), "CREATE ROLE postgres;"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), "CREATE ROLE adcm;\r\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
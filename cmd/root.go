@@ -16,11 +16,15 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path"
 	"runtime"
+	"strings"
+
+	"github.com/arenadata/adcm-installer/pkg/registry-client"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -78,6 +82,32 @@ func configFileFlags(cmd *cobra.Command) {
 	cmd.Flags().StringP("file", "f", "", "Application configuration file")
 }
 
+func registryFlags(cmd *cobra.Command) {
+	cmd.Flags().String("registry-user", "", "Registry username")
+	cmd.Flags().Bool("registry-password-stdin", false, "Read the registry password from stdin")
+	cmd.MarkFlagsRequiredTogether("registry-user", "registry-password-stdin")
+}
+
+// registryCredentials returns the credentials passed by flags or nil, in which
+// case the registry client reads them from the docker config.
+func registryCredentials(cmd *cobra.Command) (client.Credentials, error) {
+	user, _ := cmd.Flags().GetString("registry-user")
+	if len(user) == 0 {
+		return nil, nil
+	}
+
+	b, err := io.ReadAll(cmd.InOrStdin())
+	if err != nil {
+		return nil, err
+	}
+	password := strings.TrimRight(string(b), "\r\n")
+	if len(password) == 0 {
+		return nil, errors.New("registry password is empty")
+	}
+
+	return client.NewAuthBasic(user, password), nil
+}
+
 func getBool(cmd *cobra.Command, key string) bool {
 	ok, _ := cmd.Flags().GetBool(key)
 	return ok
@@ -16,6 +16,7 @@
 package cmd
 
 import (
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -26,6 +27,7 @@ import (
 	"github.com/arenadata/adcm-installer/pkg/secrets"
 	"github.com/arenadata/adcm-installer/pkg/utils"
 
+	"github.com/AlecAivazis/survey/v2"
 	composeTypes "github.com/compose-spec/compose-go/v2/types"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
@@ -64,7 +66,8 @@ func ageKeyFlags(cmd *cobra.Command, key, defaultKeyPath string, markFlags ...fu
 	fileKey := key + "-file"
 	envKey := ageEnvKey(key)
 	cmd.Flags().String(key, "", "Set private age key. Can be set by "+envKey+" environment variable")
-	cmd.Flags().String(fileKey, defaultKeyPath, "Read private age key from file")
+	cmd.Flags().String(fileKey, defaultKeyPath, "Read private age or ssh key from file. A passphrase can be set by "+
+		agePassphraseEnvKey(key)+" environment variable")
 
 	cmd.MarkFlagsMutuallyExclusive(key, fileKey)
 
@@ -117,27 +120,53 @@ func readAgeKeyFromFile(path string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	for _, line := range strings.Split(string(b), "\n") {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "#") {
-			continue
-		}
-		return strings.TrimSpace(line), nil
+	if len(strings.TrimSpace(string(b))) == 0 {
+		return "", fmt.Errorf("no age key found")
 	}
 
-	return "", fmt.Errorf("no age key found")
+	return string(b), nil
 }
 
-func readOrCreateNewAgeKey(cmd *cobra.Command, key string) (*secrets.AgeCrypt, bool, error) {
+// readAgeCrypt reads the identity set by the key flags. Passphrase protected
+// identities are unlocked with the <KEY>_PASSPHRASE environment variable or
+// an interactive prompt.
+func readAgeCrypt(cmd *cobra.Command, key string) (*secrets.AgeCrypt, error) {
 	ageKey, err := getAgeKey(cmd, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return secrets.ParseAgeIdentity([]byte(ageKey), agePassphrase(key))
+}
+
+func agePassphraseEnvKey(key string) string {
+	return ageEnvKey(key) + "_PASSPHRASE"
+}
+
+func agePassphrase(key string) secrets.PassphraseFunc {
+	return func() (string, error) {
+		envKey := agePassphraseEnvKey(key)
+		if pass := os.Getenv(envKey); len(pass) > 0 {
+			_ = os.Unsetenv(envKey)
+			return pass, nil
+		}
+
+		var pass string
+		err := survey.AskOne(&survey.Password{Message: fmt.Sprintf("Passphrase for %s:", key)}, &pass,
+			survey.WithValidator(survey.Required))
+		return pass, err
+	}
+}
+
+func readOrCreateNewAgeKey(cmd *cobra.Command, key string) (*secrets.AgeCrypt, bool, error) {
+	cryptKey, err := readAgeCrypt(cmd, key)
 	if err != nil && !errors.Is(err, noAgeKeyProvided) {
 		return nil, false, err
 	} else if err == nil {
-		cryptKey, err := secrets.NewAgeCryptFromString(ageKey)
-		return cryptKey, false, err
+		return cryptKey, false, nil
 	}
 
-	cryptKey, err := secrets.NewAgeCrypt()
+	cryptKey, err = secrets.NewAgeCrypt()
 	return cryptKey, true, err
 }
 
@@ -155,30 +184,66 @@ func toYaml(output io.Writer, v any) (err error) {
 
 func encoder(cmd *cobra.Command, prj *composeTypes.Project) (secrets.Secrets, error) {
 	xSecrets, ok := prj.Extensions[services.XSecretsKey]
-	if ok {
+	if ok && len(xSecrets.(*services.XSecrets).Key) > 0 {
 		sec := xSecrets.(*services.XSecrets)
 
-		ageKey, err := getAgeKey(cmd, "age-key")
+		dec, err := readAgeCrypt(cmd, "age-key")
 		if err != nil {
 			return nil, err
 		}
 
-		dec, err := secrets.NewAgeCryptFromString(ageKey)
+		aesKey, err := decryptMasterKey(sec, dec)
 		if err != nil {
 			return nil, err
 		}
 
-		if sec.AgeRecipient != dec.Recipient().String() {
-			return nil, fmt.Errorf("age_recipient not match")
-		}
+		return secrets.NewAesCrypt([]byte(aesKey))
+	}
 
-		aesKey, err := dec.Decrypt(sec.Key)
-		if err != nil {
-			return nil, err
+	return nil, nil
+}
+
+// storeMacKey encrypts the key for fingerprinting secrets into the project
+// x-secrets. The key is kept apart from the data key, so the fingerprints in
+// the container labels and in the init job markers survive the data key
+// rotation.
+func storeMacKey(sec *services.XSecrets, key []byte, aes secrets.Secrets) error {
+	v, err := aes.EncryptValue(hex.EncodeToString(key), caSecretsScope, macKeySecret)
+	if err != nil {
+		return err
+	}
+	if sec.Data == nil {
+		sec.Data = map[string]string{}
+	}
+	sec.Data[macKeySecret] = v
+	return nil
+}
+
+// readMacKey returns the key for fingerprinting secrets. The configuration
+// files created without the stored key use the one derived from the data key.
+// Without encryption the secrets are in clear text in the configuration file
+// anyway.
+func readMacKey(prj *composeTypes.Project, aes secrets.Secrets) ([]byte, error) {
+	if aes == nil {
+		return nil, nil
+	}
+
+	if ext, ok := prj.Extensions[services.XSecretsKey]; ok {
+		if v, ok := ext.(*services.XSecrets).Data[macKeySecret]; ok {
+			key, err := aes.DecryptValue(v, caSecretsScope, macKeySecret)
+			if err != nil {
+				return nil, err
+			}
+			return hex.DecodeString(key)
 		}
+	}
+	return aes.MacKey(), nil
+}
 
-		return secrets.NewAesCrypt([]byte(aesKey))
+func decryptMasterKey(sec *services.XSecrets, dec *secrets.AgeCrypt) (string, error) {
+	if !sec.HasRecipient(dec.Recipient()) {
+		return "", fmt.Errorf("age key does not match any of age recipients")
 	}
 
-	return nil, nil
+	return dec.Decrypt(sec.Key)
 }
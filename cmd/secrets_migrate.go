@@ -0,0 +1,117 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/arenadata/adcm-installer/internal/services"
+	"github.com/arenadata/adcm-installer/pkg/secrets"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Upgrade encrypted values to the current format",
+	Long: `Re-encrypts x-secrets values stored in the legacy unversioned format. Values
+in the current format are bound to the service name and secret key, so they
+cannot be copied to another place of the configuration file. The configuration
+file is rewritten atomically.
+- --age-key takes the value of the private key in clear text. Has priority over
+            --age-key-file
+- --age-key-file takes the value of the path to the file with the private key
+- --file specifies the path to the configuration file`,
+	Run: secretsMigrate,
+}
+
+func init() {
+	secretsCmd.AddCommand(migrateCmd)
+
+	ageKeyFlags(migrateCmd, "age-key", ageKeyFileName)
+	configFileFlags(migrateCmd)
+}
+
+func secretsMigrate(cmd *cobra.Command, _ []string) {
+	logger := log.WithField("command", "secrets-migrate")
+
+	configFilePath, _ := cmd.Flags().GetString("file")
+	prj, err := readConfigFile(configFilePath)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if len(configFilePath) == 0 {
+		configFilePath = prj.ComposeFiles[0]
+	}
+
+	aes, err := encoder(cmd, prj)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if aes == nil {
+		logger.Fatal("Configuration file has no encrypted values")
+	}
+
+	var count int
+	for svcName, service := range prj.Services {
+		ext, ok := service.Extensions[services.XSecretsKey]
+		if !ok {
+			continue
+		}
+		xsec := ext.(*services.XSecrets)
+
+		for _, m := range []map[string]string{xsec.Data, xsec.UnMapped} {
+			n, err := migrateValues(aes, svcName, m)
+			if err != nil {
+				logger.Fatalf("service %s: %v", svcName, err)
+			}
+			count += n
+		}
+	}
+
+	if count == 0 {
+		logger.Info("All values are up to date")
+		return
+	}
+
+	if err = writeConfigFile(configFilePath, prj); err != nil {
+		logger.Fatal(err)
+	}
+
+	logger.Infof("%d values migrated", count)
+}
+
+func migrateValues(aes secrets.Secrets, svcName string, m map[string]string) (int, error) {
+	var count int
+	for k, v := range m {
+		if !secrets.IsLegacyValue(v) {
+			continue
+		}
+
+		plain, err := aes.DecryptValue(v, svcName, k)
+		if err != nil {
+			return count, fmt.Errorf("decrypt %s: %v", k, err)
+		}
+
+		if m[k], err = aes.EncryptValue(plain, svcName, k); err != nil {
+			return count, fmt.Errorf("encrypt %s: %v", k, err)
+		}
+		count++
+	}
+
+	return count, nil
+}
@@ -16,6 +16,7 @@
 package cmd
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"os"
@@ -23,6 +24,7 @@ import (
 
 	"github.com/arenadata/adcm-installer/pkg/secrets"
 
+	"github.com/AlecAivazis/survey/v2"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -32,7 +34,12 @@ var newKeyCmd = &cobra.Command{
 	Short: "Generate a new private key",
 	Long: `Creates a new file age.key. The command will fail if the file
 age.key exists.
-- --output - specifies the path to save the file with the private key`,
+- --output - specifies the path to save the file with the private key, only
+             the public key is printed then. With - the private key is
+             printed, encrypted if --passphrase is set
+- --passphrase - encrypts the saved file with a passphrase (scrypt). The
+                 passphrase is read from AGE_KEY_PASSPHRASE environment
+                 variable or prompted interactively`,
 	Run: secretsNewKey,
 }
 
@@ -40,6 +47,7 @@ func init() {
 	secretsCmd.AddCommand(newKeyCmd)
 
 	newKeyCmd.Flags().StringP("output", "o", ageKeyFileName, "Key output filename")
+	newKeyCmd.Flags().Bool("passphrase", false, "Protect the key file with a passphrase")
 }
 
 func secretsNewKey(cmd *cobra.Command, _ []string) {
@@ -50,33 +58,83 @@ func secretsNewKey(cmd *cobra.Command, _ []string) {
 		logger.Fatal(err)
 	}
 
+	var passphrase string
+	if getBool(cmd, "passphrase") {
+		if passphrase, err = newAgePassphrase("age-key"); err != nil {
+			logger.Fatal(err)
+		}
+	}
+
+	// the private key is printed in clear text only when it is not saved and
+	// not protected
 	outputPath, _ := cmd.Flags().GetString("output")
 	if len(outputPath) > 0 && outputPath != "-" {
-		if err = saveAgeKey(outputPath, age); err != nil {
+		if err = saveAgeKey(outputPath, age, passphrase); err != nil {
+			logger.Fatal(err)
+		}
+		fmt.Printf("# public key: %s\n", age.Recipient())
+		return
+	}
+
+	if len(passphrase) > 0 {
+		data, err := ageKeyData(age, passphrase)
+		if err != nil {
 			logger.Fatal(err)
 		}
+		fmt.Fprintf(os.Stderr, "# public key: %s\n", age.Recipient())
+		fmt.Print(data)
+		return
 	}
 
 	_ = fPrintAgeKey(os.Stdout, os.Stderr, age)
 }
 
-func saveAgeKey(path string, key *secrets.AgeCrypt) error {
+func newAgePassphrase(key string) (string, error) {
+	envKey := agePassphraseEnvKey(key)
+	if pass := os.Getenv(envKey); len(pass) > 0 {
+		return pass, nil
+	}
+
+	var pass, confirm string
+	if err := survey.AskOne(&survey.Password{Message: "Passphrase:"}, &pass,
+		survey.WithValidator(survey.Required)); err != nil {
+		return "", err
+	}
+	if err := survey.AskOne(&survey.Password{Message: "Confirm passphrase:"}, &confirm); err != nil {
+		return "", err
+	}
+	if pass != confirm {
+		return "", fmt.Errorf("passphrases do not match")
+	}
+
+	return pass, nil
+}
+
+func saveAgeKey(path string, key *secrets.AgeCrypt, passphrase string) error {
 	if _, err := os.Stat(path); err == nil {
 		return fmt.Errorf("file %s already exists", path)
 	}
-	fi, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0400)
+
+	data, err := ageKeyData(key, passphrase)
 	if err != nil {
 		return err
 	}
-	defer func() {
-		if e := fi.Close(); e != nil {
-			err = e
-		}
-	}()
 
-	err = fPrintAgeKey(fi, fi, key)
+	return os.WriteFile(path, []byte(data), 0400)
+}
 
-	return err
+// ageKeyData returns the content of the key file, encrypted if the passphrase
+// is set.
+func ageKeyData(key *secrets.AgeCrypt, passphrase string) (string, error) {
+	buf := new(bytes.Buffer)
+	if err := fPrintAgeKey(buf, buf, key); err != nil {
+		return "", err
+	}
+
+	if len(passphrase) == 0 {
+		return buf.String(), nil
+	}
+	return secrets.EncryptWithPassphrase(buf.String(), passphrase)
 }
 
 func fPrintAgeKey(stdout, stderr io.Writer, key *secrets.AgeCrypt) error {
@@ -0,0 +1,92 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/arenadata/adcm-installer/internal/services"
+	"github.com/arenadata/adcm-installer/pkg/secrets"
+
+	composeTypes "github.com/compose-spec/compose-go/v2/types"
+	"github.com/spf13/cobra"
+)
+
+var recipientsCmd = &cobra.Command{
+	Aliases: []string{"rcpt"},
+	Use:     "recipients",
+	Short:   "Manage age recipients of the master key",
+	Long: `The master key used to encrypt x-secrets values may be encrypted for several
+age recipients. Any private key matching one of the recipients is able to
+decrypt the configuration file.`,
+}
+
+func init() {
+	secretsCmd.AddCommand(recipientsCmd)
+}
+
+type masterKeyFile struct {
+	path      string
+	prj       *composeTypes.Project
+	masterKey *services.XSecrets
+}
+
+func readMasterKeyFile(cmd *cobra.Command) (*masterKeyFile, error) {
+	configFilePath, _ := cmd.Flags().GetString("file")
+	prj, err := readConfigFile(configFilePath)
+	if err != nil {
+		return nil, err
+	}
+	if len(configFilePath) == 0 {
+		configFilePath = prj.ComposeFiles[0]
+	}
+
+	ext, ok := prj.Extensions[services.XSecretsKey]
+	if !ok {
+		return nil, fmt.Errorf("project %s has no %s", prj.Name, services.XSecretsKey)
+	}
+
+	return &masterKeyFile{
+		path:      configFilePath,
+		prj:       prj,
+		masterKey: ext.(*services.XSecrets),
+	}, nil
+}
+
+// rewrap decrypts the master key with the age key and encrypts it again for
+// the given recipients list.
+func (f *masterKeyFile) rewrap(cmd *cobra.Command, recipients []string) error {
+	if len(recipients) == 0 {
+		return fmt.Errorf("at least one age recipient is required")
+	}
+
+	dec, err := readAgeCrypt(cmd, "age-key")
+	if err != nil {
+		return err
+	}
+
+	aesKey, err := decryptMasterKey(f.masterKey, dec)
+	if err != nil {
+		return err
+	}
+
+	if f.masterKey.Key, err = secrets.EncryptForRecipients(aesKey, recipients...); err != nil {
+		return err
+	}
+	f.masterKey.SetRecipients(recipients)
+
+	return writeConfigFile(f.path, f.prj)
+}
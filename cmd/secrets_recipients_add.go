@@ -0,0 +1,71 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cmd
+
+import (
+	"slices"
+
+	"github.com/arenadata/adcm-installer/pkg/secrets"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var recipientsAddCmd = &cobra.Command{
+	Use:   "add <recipient>...",
+	Short: "Add age recipients to the master key",
+	Long: `Encrypts the master key for the additional age public keys. ssh-ed25519 and
+ssh-rsa public keys are accepted as well. The private key must match one of the
+current recipients.
+- --age-key takes the value of the private key in clear text. Has priority over
+            --age-key-file
+- --age-key-file takes the value of the path to the file with the private key
+- --file specifies the path to the configuration file`,
+	PreRunE: cobra.MinimumNArgs(1),
+	Run:     secretsRecipientsAdd,
+}
+
+func init() {
+	recipientsCmd.AddCommand(recipientsAddCmd)
+
+	ageKeyFlags(recipientsAddCmd, "age-key", ageKeyFileName)
+	configFileFlags(recipientsAddCmd)
+}
+
+func secretsRecipientsAdd(cmd *cobra.Command, args []string) {
+	logger := log.WithField("command", "secrets-recipients-add")
+
+	f, err := readMasterKeyFile(cmd)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	recipients := f.masterKey.Recipients()
+	for _, r := range args {
+		if r, err = secrets.NormalizeRecipient(r); err != nil {
+			logger.Fatal(err)
+		}
+		if slices.Contains(recipients, r) {
+			logger.Warnf("Recipient %s already exists", r)
+			continue
+		}
+		recipients = append(recipients, r)
+	}
+
+	if err = f.rewrap(cmd, recipients); err != nil {
+		logger.Fatal(err)
+	}
+}
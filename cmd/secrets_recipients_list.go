@@ -0,0 +1,49 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cmd
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var recipientsListCmd = &cobra.Command{
+	Aliases: []string{"ls"},
+	Use:     "list",
+	Short:   "List age recipients of the master key",
+	Long: `Displays the age public keys the master key is encrypted for.
+- --file specifies the path to the configuration file`,
+	Run: secretsRecipientsList,
+}
+
+func init() {
+	recipientsCmd.AddCommand(recipientsListCmd)
+
+	configFileFlags(recipientsListCmd)
+}
+
+func secretsRecipientsList(cmd *cobra.Command, _ []string) {
+	logger := log.WithField("command", "secrets-recipients-list")
+
+	f, err := readMasterKeyFile(cmd)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	for _, r := range f.masterKey.Recipients() {
+		cmd.Println(r)
+	}
+}
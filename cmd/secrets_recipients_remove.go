@@ -0,0 +1,78 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cmd
+
+import (
+	"slices"
+
+	"github.com/arenadata/adcm-installer/pkg/secrets"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var recipientsRemoveCmd = &cobra.Command{
+	Aliases: []string{"rm"},
+	Use:     "remove <recipient>...",
+	Short:   "Remove age recipients from the master key",
+	Long: `Encrypts the master key for the remaining age public keys. The last recipient
+cannot be removed.
+- --age-key takes the value of the private key in clear text. Has priority over
+            --age-key-file
+- --age-key-file takes the value of the path to the file with the private key
+- --file specifies the path to the configuration file`,
+	PreRunE: cobra.MinimumNArgs(1),
+	Run:     secretsRecipientsRemove,
+}
+
+func init() {
+	recipientsCmd.AddCommand(recipientsRemoveCmd)
+
+	ageKeyFlags(recipientsRemoveCmd, "age-key", ageKeyFileName)
+	configFileFlags(recipientsRemoveCmd)
+}
+
+func secretsRecipientsRemove(cmd *cobra.Command, args []string) {
+	logger := log.WithField("command", "secrets-recipients-remove")
+
+	f, err := readMasterKeyFile(cmd)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	var remove []string
+	for _, r := range args {
+		if r, err = secrets.NormalizeRecipient(r); err != nil {
+			logger.Fatal(err)
+		}
+		remove = append(remove, r)
+	}
+
+	var recipients []string
+	for _, r := range f.masterKey.Recipients() {
+		if !slices.Contains(remove, r) {
+			recipients = append(recipients, r)
+		}
+	}
+
+	if len(recipients) == len(f.masterKey.Recipients()) {
+		logger.Fatal("No matching recipients found")
+	}
+
+	if err = f.rewrap(cmd, recipients); err != nil {
+		logger.Fatal(err)
+	}
+}
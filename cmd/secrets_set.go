@@ -78,7 +78,7 @@ func secretSetValue(cmd *cobra.Command, args []string) {
 		logger.Fatal(err)
 	}
 	if aes != nil {
-		value, err = aes.EncryptValue(value)
+		value, err = aes.EncryptValue(value, svcName, secKey)
 		if err != nil {
 			logger.Fatal(err)
 		}
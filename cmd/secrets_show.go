@@ -81,13 +81,13 @@ func secretsDecrypt(serviceList composeTypes.Services, dec secrets.Secrets) (map
 		svc, ok := service.Extensions[services.XSecretsKey]
 		if ok {
 			xsec := svc.(*services.XSecrets)
-			s, err := decrypt(dec, xsec.Data)
+			s, err := decrypt(dec, svcName, xsec.Data)
 			if err != nil {
 				return nil, nil, err
 			}
 			sec[svcName] = s
 
-			un, err := decrypt(dec, xsec.UnMapped)
+			un, err := decrypt(dec, svcName, xsec.UnMapped)
 			if err != nil {
 				return nil, nil, err
 			}
@@ -97,7 +97,7 @@ func secretsDecrypt(serviceList composeTypes.Services, dec secrets.Secrets) (map
 	return sec, unMappedSec, nil
 }
 
-func decrypt(dec secrets.Secrets, m map[string]string) (map[string]string, error) {
+func decrypt(dec secrets.Secrets, svcName string, m map[string]string) (map[string]string, error) {
 	if m == nil {
 		return nil, nil
 	}
@@ -108,7 +108,7 @@ func decrypt(dec secrets.Secrets, m map[string]string) (map[string]string, error
 	var err error
 	out := map[string]string{}
 	for k, v := range m {
-		v, err = dec.DecryptValue(v)
+		v, err = dec.DecryptValue(v, svcName, k)
 		if err != nil {
 			return nil, err
 		}
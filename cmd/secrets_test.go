@@ -0,0 +1,101 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/arenadata/adcm-installer/internal/services"
+	"github.com/arenadata/adcm-installer/pkg/secrets"
+
+	composeTypes "github.com/compose-spec/compose-go/v2/types"
+)
+
+func newTestDataKey(t *testing.T) ([]byte, *secrets.AesCrypt) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	aes, err := secrets.NewAesCrypt(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, aes
+}
+
+func macKeyProject(sec *services.XSecrets) *composeTypes.Project {
+	return &composeTypes.Project{Extensions: composeTypes.Extensions{services.XSecretsKey: sec}}
+}
+
+func TestReadMacKey(t *testing.T) {
+	_, aes := newTestDataKey(t)
+
+	key, err := readMacKey(macKeyProject(&services.XSecrets{}), aes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(key, aes.MacKey()) {
+		t.Errorf("readMacKey() without stored key = %x, want the derived key", key)
+	}
+
+	sec := &services.XSecrets{}
+	stored := []byte("0123456789abcdef0123456789abcdef")
+	if err = storeMacKey(sec, stored, aes); err != nil {
+		t.Fatal(err)
+	}
+	if key, err = readMacKey(macKeyProject(sec), aes); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(key, stored) {
+		t.Errorf("readMacKey() = %x, want %x", key, stored)
+	}
+
+	if key, err = readMacKey(macKeyProject(sec), nil); err != nil || key != nil {
+		t.Errorf("readMacKey() without encryption = %x, %v", key, err)
+	}
+}
+
+func TestMacKeyDataKeyRotation(t *testing.T) {
+	oldKey, oldAes := newTestDataKey(t)
+	newKey, newAes := newTestDataKey(t)
+
+	sec := &services.XSecrets{}
+	if err := keepMacKey(sec, oldKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := reEncryptCA(sec, oldKey, newKey); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := readMacKey(macKeyProject(sec), newAes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(key, oldAes.MacKey()) {
+		t.Errorf("readMacKey() after rotation = %x, want the key of the old data key %x", key, oldAes.MacKey())
+	}
+
+	// the stored key is not replaced by the next rotation
+	before := sec.Data[macKeySecret]
+	if err = keepMacKey(sec, newKey); err != nil {
+		t.Fatal(err)
+	}
+	if sec.Data[macKeySecret] != before {
+		t.Errorf("keepMacKey() replaced the stored key")
+	}
+}
@@ -16,14 +16,24 @@
 package cmd
 
 import (
+	"crypto/rand"
+	"fmt"
+	"slices"
+
+	"github.com/arenadata/adcm-installer/internal/services"
+	"github.com/arenadata/adcm-installer/pkg/secrets"
+
+	composeTypes "github.com/compose-spec/compose-go/v2/types"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
 var updateKeyCmd = &cobra.Command{
 	Use:   "update-key",
-	Short: "NOT IMPLEMENTED",
-	Long: `Allows you to rotate the private key.
+	Short: "Rotate the private key",
+	Long: `Allows you to rotate the private key. The master key stored in the project
+x-secrets is decrypted with the old private key and encrypted for the new one.
+The configuration file is rewritten atomically.
 - --file specifies the path to the configuration file
 - --new-age-key takes the value of the private key in clear text. Has priority
                 over --new-age-key-file
@@ -32,7 +42,10 @@ var updateKeyCmd = &cobra.Command{
 - --old-age-key takes the value of the private key in clear text. Has priority
                 over --old-age-key-file
 - --old-age-key-file takes the value of the path to the file with the private
-                     key`,
+                     key
+- --rotate-data-key generates a new master key and re-encrypts all services
+                    secrets with it. The key for fingerprinting secrets is
+                    kept, so adi apply does not recreate the containers`,
 	Run: secretsUpdateKey,
 }
 
@@ -43,8 +56,131 @@ func init() {
 	ageKeyFlags(updateKeyCmd, "new-age-key", "", updateKeyCmd.MarkFlagsOneRequired)
 
 	configFileFlags(updateKeyCmd)
+	updateKeyCmd.Flags().Bool("rotate-data-key", false, "Generate a new master key and re-encrypt all secrets")
 }
 
 func secretsUpdateKey(cmd *cobra.Command, _ []string) {
-	log.Fatal("not implemented")
+	logger := log.WithField("command", "secrets-update-key")
+
+	f, err := readMasterKeyFile(cmd)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	masterKey := f.masterKey
+
+	oldAge, err := readAgeCrypt(cmd, "old-age-key")
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	newAge, err := readAgeCrypt(cmd, "new-age-key")
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	aesKey, err := decryptMasterKey(masterKey, oldAge)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	if getBool(cmd, "rotate-data-key") {
+		key := make([]byte, 32)
+		if _, err = rand.Read(key); err != nil {
+			logger.Fatal(err)
+		}
+
+		if err = keepMacKey(masterKey, []byte(aesKey)); err != nil {
+			logger.Fatal(err)
+		}
+		if err = reEncryptServices(f.prj.Services, []byte(aesKey), key); err != nil {
+			logger.Fatal(err)
+		}
+		if err = reEncryptCA(masterKey, []byte(aesKey), key); err != nil {
+			logger.Fatal(err)
+		}
+		aesKey = string(key)
+	}
+
+	oldRecipient := oldAge.Recipient()
+	newRecipient := newAge.Recipient()
+	var recipients []string
+	for _, r := range masterKey.Recipients() {
+		if r == oldRecipient {
+			r = newRecipient
+		}
+		if !slices.Contains(recipients, r) {
+			recipients = append(recipients, r)
+		}
+	}
+
+	if masterKey.Key, err = secrets.EncryptForRecipients(aesKey, recipients...); err != nil {
+		logger.Fatal(err)
+	}
+	masterKey.SetRecipients(recipients)
+
+	if err = writeConfigFile(f.path, f.prj); err != nil {
+		logger.Fatal(err)
+	}
+
+	logger.Infof("Private key rotated. Replace the old private key with the new one (public key: %s)",
+		newRecipient)
+}
+
+// keepMacKey stores the key for fingerprinting secrets derived from the old
+// data key, if the configuration file has none, so the fingerprints do not
+// change with the data key. It is re-encrypted by reEncryptCA with the rest of
+// the project x-secrets.
+func keepMacKey(sec *services.XSecrets, oldKey []byte) error {
+	if _, ok := sec.Data[macKeySecret]; ok {
+		return nil
+	}
+
+	aes, err := secrets.NewAesCrypt(oldKey)
+	if err != nil {
+		return err
+	}
+	return storeMacKey(sec, aes.MacKey(), aes)
+}
+
+func reEncryptServices(serviceList composeTypes.Services, oldKey, newKey []byte) error {
+	dec, err := secrets.NewAesCrypt(oldKey)
+	if err != nil {
+		return err
+	}
+	enc, err := secrets.NewAesCrypt(newKey)
+	if err != nil {
+		return err
+	}
+
+	for svcName, service := range serviceList {
+		ext, ok := service.Extensions[services.XSecretsKey]
+		if !ok {
+			continue
+		}
+		xsec := ext.(*services.XSecrets)
+
+		if err = reEncrypt(svcName, xsec.Data, dec, enc); err != nil {
+			return fmt.Errorf("service %s: %v", svcName, err)
+		}
+		if err = reEncrypt(svcName, xsec.UnMapped, dec, enc); err != nil {
+			return fmt.Errorf("service %s: %v", svcName, err)
+		}
+	}
+
+	return nil
+}
+
+func reEncrypt(svcName string, m map[string]string, dec, enc secrets.Secrets) error {
+	for k, v := range m {
+		plain, err := dec.DecryptValue(v, svcName, k)
+		if err != nil {
+			return fmt.Errorf("decrypt %s: %v", k, err)
+		}
+
+		if m[k], err = enc.EncryptValue(plain, svcName, k); err != nil {
+			return fmt.Errorf("encrypt %s: %v", k, err)
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,294 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arenadata/adcm-installer/internal/services"
+	"github.com/arenadata/adcm-installer/pkg/compose"
+	"github.com/arenadata/adcm-installer/pkg/vault/unseal/runner"
+
+	cliFormatter "github.com/docker/cli/cli/command/formatter"
+	"github.com/docker/compose/v2/cmd/formatter"
+	"github.com/docker/compose/v2/pkg/api"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-units"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const notAvailable = "-"
+
+type serviceView struct {
+	Service  string
+	State    string
+	Health   string
+	Image    string
+	Digest   string
+	Ports    string
+	Uptime   string
+	Restarts string
+	Config   string
+}
+
+var statusCmd = &cobra.Command{
+	Use:   "status [name]",
+	Short: "Show the status of the installation services",
+	Long: `Displays every service of the installation, including init containers, with
+the container state, health check status, image and digest, published ports,
+uptime and restart count. The CONFIG column shows whether the running container
+matches the configuration file: "drifted" means adi apply would recreate it.
+The seal status of every Vault node, and whether it is the active or a standby
+node of the ha mode, is displayed below the table. Without arguments, the
+current directory's adcm.yaml (adcm.yml/ad-app.yml/ad-app.yaml) is used. If the
+name of an installation is specified, the configuration is read only with the
+--file flag, otherwise the CONFIG column is not displayed.
+- --age-key takes the value of the private key in clear text. Has priority over
+            --age-key-file
+- --age-key-file takes the value of the path to the file with the private key
+- --file specifies the path to the configuration file`,
+	PreRunE: cobra.MaximumNArgs(1),
+	Run:     statusProject,
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+
+	ageKeyFlags(statusCmd, "age-key", ageKeyFileName)
+	configFileFlags(statusCmd)
+}
+
+func statusProject(cmd *cobra.Command, args []string) {
+	logger := log.WithField("command", "status")
+	ctx := cmd.Context()
+
+	var prjName string
+	var expected map[string]string
+	configFilePath, _ := cmd.Flags().GetString("file")
+	if len(args) > 0 {
+		prjName = args[0]
+	}
+
+	if len(args) == 0 || len(configFilePath) > 0 {
+		d, err := newDeployment(cmd, true)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		if len(prjName) > 0 && prjName != d.prj.Name {
+			logger.Fatalf("Configuration file describes %s, not %s", d.prj.Name, prjName)
+		}
+		prjName = d.prj.Name
+
+		if expected, err = serviceHashes(d); err != nil {
+			logger.Fatal(err)
+		}
+	}
+
+	comp, err := compose.NewComposeService()
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	containers, err := comp.ProjectContainers(ctx, prjName)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if len(containers) == 0 && expected == nil {
+		logger.Fatalf("Installation %s not found", prjName)
+	}
+
+	view, vaultContainers, err := servicesStatus(ctx, comp, containers, expected)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	header := []string{"SERVICE", "STATE", "HEALTH", "IMAGE", "DIGEST", "PORTS", "UPTIME", "RESTARTS"}
+	if expected != nil {
+		header = append(header, "CONFIG")
+	}
+
+	err = formatter.Print(view, formatter.TABLE, cmd.OutOrStdout(), func(w io.Writer) {
+		for _, s := range view {
+			row := []string{s.Service, s.State, s.Health, s.Image, s.Digest, s.Ports, s.Uptime, s.Restarts}
+			if expected != nil {
+				row = append(row, s.Config)
+			}
+			_, _ = fmt.Fprintln(w, strings.Join(row, "\t"))
+		}
+	}, header...)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	if len(vaultContainers) > 0 {
+		cmd.Println()
+	}
+	if name, ok := vaultContainers[services.VaultName]; ok && len(vaultContainers) == 1 {
+		cmd.Println("Vault:", vaultStatus(ctx, name))
+	} else {
+		nodes := make([]string, 0, len(vaultContainers))
+		for node := range vaultContainers {
+			nodes = append(nodes, node)
+		}
+		sort.Strings(nodes)
+		for _, node := range nodes {
+			cmd.Printf("Vault %s: %s\n", node, vaultStatus(ctx, vaultContainers[node]))
+		}
+	}
+}
+
+// serviceHashes returns the configuration hashes docker compose would set on
+// the containers of the deployment.
+func serviceHashes(d *deployment) (map[string]string, error) {
+	hashes := make(map[string]string, len(d.prj.Services))
+	for name, svc := range d.prj.Services {
+		hash, err := compose.ServiceHash(svc)
+		if err != nil {
+			return nil, err
+		}
+		hashes[name] = hash
+	}
+	return hashes, nil
+}
+
+// servicesStatus builds a table row for every container, and for every
+// configured service without a container. It also returns the names of the
+// running Vault containers by service name.
+func servicesStatus(ctx context.Context, comp *compose.Compose, containers []container.Summary, expected map[string]string) ([]serviceView, map[string]string, error) {
+	var view []serviceView
+	vaultContainers := map[string]string{}
+	seen := map[string]bool{}
+
+	for _, c := range containers {
+		name := c.Labels[api.ServiceLabel]
+		seen[name] = true
+
+		inspect, err := comp.Inspect(ctx, c.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		s := serviceView{
+			Service:  name,
+			State:    c.State,
+			Health:   notAvailable,
+			Image:    c.Image,
+			Digest:   notAvailable,
+			Ports:    cliFormatter.DisplayablePorts(c.Ports),
+			Uptime:   notAvailable,
+			Restarts: strconv.Itoa(inspect.RestartCount),
+			Config:   notAvailable,
+		}
+
+		if st := inspect.State; st != nil {
+			if st.Health != nil {
+				s.Health = st.Health.Status
+			}
+			if st.Running {
+				if started, err := time.Parse(time.RFC3339Nano, st.StartedAt); err == nil {
+					s.Uptime = units.HumanDuration(time.Since(started))
+				}
+			} else if st.Status == container.StateExited {
+				s.State = fmt.Sprintf("%s (%d)", st.Status, st.ExitCode)
+			}
+		}
+
+		if digest, err := comp.ImageDigest(ctx, c.Image); err == nil {
+			s.Digest = shortDigest(digest)
+		}
+
+		if hash, ok := expected[name]; ok {
+			s.Config = "up to date"
+			if c.Labels[api.ConfigHashLabel] != hash {
+				s.Config = "drifted"
+			}
+		} else if expected != nil {
+			s.Config = "orphaned"
+		}
+
+		if c.Labels[compose.ADAppTypeLabelKey] == services.VaultName && c.State == container.StateRunning {
+			vaultContainers[name] = strings.TrimPrefix(c.Names[0], "/")
+		}
+
+		view = append(view, s)
+	}
+
+	for name := range expected {
+		if seen[name] {
+			continue
+		}
+		view = append(view, serviceView{
+			Service:  name,
+			State:    "absent",
+			Health:   notAvailable,
+			Image:    notAvailable,
+			Digest:   notAvailable,
+			Ports:    notAvailable,
+			Uptime:   notAvailable,
+			Restarts: notAvailable,
+			Config:   notAvailable,
+		})
+	}
+
+	sort.SliceStable(view, func(i, j int) bool { return view[i].Service < view[j].Service })
+	return view, vaultContainers, nil
+}
+
+// shortDigest cuts repo@sha256:<hex> down to sha256:<12 hex chars>.
+func shortDigest(digest string) string {
+	if i := strings.LastIndex(digest, "@"); i >= 0 {
+		digest = digest[i+1:]
+	}
+	if algo, hex, ok := strings.Cut(digest, ":"); ok && len(hex) > 12 {
+		return algo + ":" + hex[:12]
+	}
+	return digest
+}
+
+func vaultStatus(ctx context.Context, containerName string) string {
+	r, err := runner.New(runner.ViaContainer, runner.Target{Container: containerName})
+	if err != nil {
+		return err.Error()
+	}
+
+	status, err := r.Status(ctx)
+	if err != nil {
+		return err.Error()
+	}
+
+	switch {
+	case !status.Initialized:
+		return "not initialized"
+	case status.Sealed:
+		return fmt.Sprintf("sealed, unseal progress %d/%d", status.Progress, status.T)
+	}
+
+	s := fmt.Sprintf("unsealed, version %s, storage %s", status.Version, status.StorageType)
+	if status.HAEnabled {
+		if status.IsSelf {
+			return s + ", active"
+		}
+		return s + ", standby"
+	}
+	return s
+}
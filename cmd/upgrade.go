@@ -0,0 +1,396 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/arenadata/adcm-installer/internal/services"
+	"github.com/arenadata/adcm-installer/pkg/backup"
+	"github.com/arenadata/adcm-installer/pkg/compose"
+	"github.com/arenadata/adcm-installer/pkg/registry-client"
+	"github.com/arenadata/adcm-installer/pkg/utils"
+
+	"github.com/blang/semver/v4"
+	composeTypes "github.com/compose-spec/compose-go/v2/types"
+	"github.com/distribution/reference"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var upgradeCmd = &cobra.Command{
+	Use:   "upgrade",
+	Short: "Upgrade ADCM to a new version",
+	Long: `Upgrades all ADCM instances of the installation to the specified version. The
+version must be available in the ADCM image repository and must not be lower
+than the current one. Before the upgrade a backup with the ADPG dump and the
+ADCM volumes is taken, then the new image is pulled and the instances are
+restarted batch by batch, each batch must become healthy before the next one
+is started: an instance is healthy when the ADCM API answers its health check,
+not as soon as the container is running. If an instance fails, the previous
+image tag, the ADCM volumes and the databases of the upgraded instances are
+restored from the backup, the other databases of ADPG (e.g. of Vault) are not
+touched.
+- --age-key takes the value of the private key in clear text. Has priority over
+            --age-key-file
+- --age-key-file takes the value of the path to the file with the private key
+- --backup-file specifies the path of the pre-upgrade backup archive,
+                <name>-<version>-<timestamp>.tar.gz by default
+- --file specifies the path to the configuration file
+- --max-unavailable specifies how many ADCM instances are restarted at once,
+                    1 by default
+- --registry-user specifies the username of the registry the ADCM versions are
+                  listed in. Credentials of the registry are read from the
+                  docker config, including credential helpers, unless passed
+                  by flags
+- --registry-password-stdin reads the registry password from stdin
+- --to specifies the target ADCM version
+- --unseal-identity specifies the private key file of a holder of a Vault unseal
+                    key share, see adi apply --help
+- --unseal-via specifies how the Vault unseal commands are run, see adi apply
+               --help
+- --wait-timeout specifies how long to wait for an instance to become healthy,
+                 5m by default. The failed ADCM health checks are not
+                 counted during the first 3m of an instance`,
+	Run: upgradeProject,
+}
+
+func init() {
+	rootCmd.AddCommand(upgradeCmd)
+
+	ageKeyFlags(upgradeCmd, "age-key", ageKeyFileName)
+	configFileFlags(upgradeCmd)
+	upgradeCmd.Flags().String("to", "", "Target ADCM version")
+	upgradeCmd.Flags().String("backup-file", "", "Pre-upgrade backup archive filename")
+	registryFlags(upgradeCmd)
+	rolloutFlags(upgradeCmd)
+	unsealIdentityFlags(upgradeCmd)
+	unsealViaFlags(upgradeCmd)
+	_ = upgradeCmd.MarkFlagRequired("to")
+}
+
+type adcmImage struct {
+	service string
+	repo    string
+	version semver.Version
+}
+
+// adcmImages returns the ADCM services of the project with their image
+// repository and version.
+func adcmImages(prj *composeTypes.Project) ([]adcmImage, error) {
+	var out []adcmImage
+	for _, name := range prj.ServiceNames() {
+		svc := prj.Services[name]
+		if svc.Labels[compose.ADAppTypeLabelKey] != services.AdcmName {
+			continue
+		}
+
+		ref, err := reference.ParseNormalizedNamed(svc.Image)
+		if err != nil {
+			return nil, fmt.Errorf("service %s: %v", name, err)
+		}
+		tagged, ok := ref.(reference.Tagged)
+		if !ok {
+			return nil, fmt.Errorf("service %s: image %s has no tag", name, svc.Image)
+		}
+		ver, err := semver.Parse(tagged.Tag())
+		if err != nil {
+			return nil, fmt.Errorf("service %s: %v", name, err)
+		}
+
+		out = append(out, adcmImage{service: name, repo: ref.Name(), version: ver})
+	}
+
+	return out, nil
+}
+
+// selectUpgrade returns the images to upgrade to the target version. The
+// versions available in an image repository are listed once with versions.
+func selectUpgrade(images []adcmImage, target semver.Version, versions func(repo string) ([]semver.Version, error)) ([]adcmImage, error) {
+	var upgrade []adcmImage
+	available := map[string]bool{}
+	for _, img := range images {
+		switch img.version.Compare(target) {
+		case 0:
+			log.Infof("%s is already at %s", img.service, target)
+			continue
+		case 1:
+			return nil, fmt.Errorf("%s: downgrade from %s to %s is not supported", img.service, img.version, target)
+		}
+
+		if _, ok := available[img.repo]; !ok {
+			list, err := versions(img.repo)
+			if err != nil {
+				return nil, err
+			}
+			available[img.repo] = containsVersion(list, target)
+		}
+		if !available[img.repo] {
+			return nil, fmt.Errorf("version %s not found in %s", target, img.repo)
+		}
+
+		upgrade = append(upgrade, img)
+	}
+
+	return upgrade, nil
+}
+
+func upgradeProject(cmd *cobra.Command, _ []string) {
+	logger := log.WithField("command", "upgrade")
+	ctx := cmd.Context()
+
+	to, _ := cmd.Flags().GetString("to")
+	target, err := semver.Parse(to)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	configFilePath, _ := cmd.Flags().GetString("file")
+	prj, err := readConfigFile(configFilePath)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if len(configFilePath) == 0 {
+		configFilePath = prj.ComposeFiles[0]
+	}
+
+	images, err := adcmImages(prj)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	cred, err := registryCredentials(cmd)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	var opts []client.RegistryOption
+	if cred != nil {
+		opts = append(opts, client.WihCredentials(cred))
+	}
+
+	upgrade, err := selectUpgrade(images, target, func(repo string) ([]semver.Version, error) {
+		return imageVersions(repo, opts...)
+	})
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if len(upgrade) == 0 {
+		return
+	}
+
+	aes, err := encoder(cmd, prj)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	xSecrets, _, err := secretsDecrypt(prj.Services, aes)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	comp, err := compose.NewComposeService()
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	logger.Info("Taking pre-upgrade backup ...")
+	arc, err := newBackup(ctx, comp, prj, xSecrets)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	defer func() { _ = arc.Close() }()
+	if err = backupAdcmDatabases(ctx, comp, prj, xSecrets, arc, upgrade); err != nil {
+		logger.Fatal(err)
+	}
+
+	backupPath, _ := cmd.Flags().GetString("backup-file")
+	if len(backupPath) == 0 {
+		backupPath = fmt.Sprintf("%s-%s-%s.tar.gz", prj.Name, upgrade[0].version,
+			arc.Manifest.Created.Format("20060102150405"))
+	}
+	if err = writeBackup(arc, backupPath); err != nil {
+		logger.Fatal(err)
+	}
+	logger.Infof("Backup saved to %s", backupPath)
+
+	for _, img := range upgrade {
+		svc := prj.Services[img.service]
+		svc.Image = img.repo + ":" + target.String()
+		prj.Services[img.service] = svc
+	}
+
+	oldConfig, err := arc.ReadFile(backupConfigFile)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if err = writeConfigFile(configFilePath, prj); err != nil {
+		logger.Fatal(err)
+	}
+
+	if err = upgradeInstances(cmd, upgrade); err != nil {
+		logger.Errorf("Upgrade failed: %v", err)
+		logger.Info("Rolling back ...")
+
+		if e := rollbackUpgrade(cmd, configFilePath, oldConfig, arc, upgrade); e != nil {
+			logger.Fatalf("Rollback failed: %v. Backup: %s", e, backupPath)
+		}
+		logger.Fatalf("Upgrade failed, rolled back to the previous version")
+	}
+
+	logger.Infof("ADCM upgraded to %s", target)
+}
+
+func containsVersion(versions []semver.Version, v semver.Version) bool {
+	for _, ver := range versions {
+		if ver.Equals(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func upgradeInstances(cmd *cobra.Command, upgrade []adcmImage) error {
+	ctx := cmd.Context()
+
+	d, err := newDeployment(cmd, true)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(upgrade))
+	for _, img := range upgrade {
+		names = append(names, img.service)
+	}
+
+	pullPrj, err := d.prj.WithSelectedServices(names, composeTypes.IgnoreDependencies)
+	if err != nil {
+		return err
+	}
+	log.Info("Pulling images ...")
+	if err = d.comp.Pull(ctx, pullPrj); err != nil {
+		return err
+	}
+
+	if err = d.runJobs(ctx, false); err != nil {
+		return err
+	}
+
+	return d.comp.Rollout(ctx, d.prj, names, d.maxUnavailable, d.upOptions()...)
+}
+
+// rollbackUpgrade stops the upgraded instances, restores their volumes and
+// databases from the pre-upgrade backup and starts the previous version.
+func rollbackUpgrade(cmd *cobra.Command, configFilePath string, oldConfig []byte, arc *backup.Archive, upgrade []adcmImage) error {
+	ctx := cmd.Context()
+
+	if err := utils.WriteFileAtomic(configFilePath, oldConfig, 0640); err != nil {
+		return err
+	}
+
+	d, err := newDeployment(cmd, true)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(upgrade))
+	for _, img := range upgrade {
+		names = append(names, img.service)
+	}
+
+	if err = d.comp.Remove(ctx, d.prj, names...); err != nil {
+		return err
+	}
+	if err = restoreVolumes(ctx, d.comp, d.prj, arc, true, names...); err != nil {
+		return err
+	}
+	if err = restoreAdcmDatabases(ctx, d, arc, names); err != nil {
+		return err
+	}
+
+	return d.up(ctx, false, false)
+}
+
+// adcmDumpFile is the archive file with the database dump of an ADCM instance.
+func adcmDumpFile(service string) string {
+	return "adpg/" + service + ".sql"
+}
+
+// backupAdcmDatabases adds the dumps of the databases of the upgraded ADCM
+// instances to the pre-upgrade backup. The whole ADPG dump cannot be used for
+// the rollback: its databases are dropped and created again, which fails while
+// Vault is connected and would roll back Vault data as well.
+func backupAdcmDatabases(ctx context.Context, comp *compose.Compose, prj *composeTypes.Project, xSecrets map[string]map[string]string, arc *backup.Archive, upgrade []adcmImage) error {
+	if _, ok := prj.Services[services.AdpgName]; !ok {
+		return nil
+	}
+
+	env := []string{"PGPASSWORD=" + xSecrets[services.AdpgName]["password"]}
+	for _, img := range upgrade {
+		db := xSecrets[img.service][services.PgDbName]
+		if len(db) == 0 {
+			continue
+		}
+
+		log.Infof("Dumping %s database %s ...", img.service, db)
+		dumpCmd := []string{"pg_dump", "--clean", "--if-exists", "-U", backupAdpgUser, "-d", db}
+		err := backupStream(arc, adcmDumpFile(img.service), func(w io.Writer) error {
+			return comp.ExecStream(ctx, prj.Name+"-"+services.AdpgName, env, dumpCmd, nil, w)
+		})
+		if err != nil {
+			return fmt.Errorf("%s database dump: %v", img.service, err)
+		}
+	}
+
+	return nil
+}
+
+// restoreAdcmDatabases loads the database dumps of the ADCM instances. Their
+// containers have to be removed, so nothing is connected to the databases.
+func restoreAdcmDatabases(ctx context.Context, d *deployment, arc *backup.Archive, names []string) error {
+	var dumps []string
+	for _, name := range names {
+		if _, ok := arc.Manifest.Files[adcmDumpFile(name)]; ok {
+			dumps = append(dumps, name)
+		}
+	}
+	if len(dumps) == 0 {
+		return nil
+	}
+
+	if err := startAdpg(ctx, d); err != nil {
+		return err
+	}
+
+	env := []string{"PGPASSWORD=" + d.xSecrets[services.AdpgName]["password"]}
+	for _, name := range dumps {
+		db := d.xSecrets[name][services.PgDbName]
+		log.Infof("Loading %s database %s ...", name, db)
+
+		dump, err := arc.Open(adcmDumpFile(name))
+		if err != nil {
+			return err
+		}
+		loadCmd := []string{"psql", "-q", "-v", "ON_ERROR_STOP=1", "-U", backupAdpgUser, "-d", db}
+		err = d.comp.ExecStream(ctx, d.prj.Name+"-"+services.AdpgName, env, loadCmd, dump, nil)
+		_ = dump.Close()
+		if err != nil {
+			return fmt.Errorf("%s database: %v", name, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,152 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/arenadata/adcm-installer/internal/services"
+	"github.com/arenadata/adcm-installer/pkg/compose"
+
+	"github.com/blang/semver/v4"
+	composeTypes "github.com/compose-spec/compose-go/v2/types"
+)
+
+func adcmProject(images map[string]string) *composeTypes.Project {
+	prj := &composeTypes.Project{Name: "adcm", Services: composeTypes.Services{}}
+	for name, img := range images {
+		prj.Services[name] = composeTypes.ServiceConfig{
+			Name:   name,
+			Image:  img,
+			Labels: composeTypes.Labels{compose.ADAppTypeLabelKey: services.AdcmName},
+		}
+	}
+	return prj
+}
+
+func TestAdcmImages(t *testing.T) {
+	prj := adcmProject(map[string]string{
+		"adcm-1": services.ADCMImage + ":2.6.0",
+		"adcm-2": "adcm:2.7.0-rc.1",
+	})
+	prj.Services[services.AdpgName] = composeTypes.ServiceConfig{Name: services.AdpgName, Image: "adpg:16"}
+
+	got, err := adcmImages(prj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []adcmImage{
+		{service: "adcm-1", repo: services.ADCMImage, version: semver.MustParse("2.6.0")},
+		{service: "adcm-2", repo: "docker.io/library/adcm", version: semver.MustParse("2.7.0-rc.1")},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("adcmImages() = %v, want %v", got, want)
+	}
+}
+
+func TestAdcmImagesErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		image string
+		want  string
+	}{
+		{"Untagged", services.ADCMImage, "has no tag"},
+		{"Digest", services.ADCMImage + "@sha256:" + strings.Repeat("a", 64), "has no tag"},
+		{"NotSemver", services.ADCMImage + ":latest", "service adcm"},
+		{"ShortVersion", services.ADCMImage + ":2.6", "service adcm"},
+		{"Invalid", "ADCM:2.6.0", "service adcm"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := adcmImages(adcmProject(map[string]string{"adcm": tt.image}))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("adcmImages() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectUpgrade(t *testing.T) {
+	const other = "registry.example.com/adcm"
+	images := []adcmImage{
+		{service: "adcm-1", repo: services.ADCMImage, version: semver.MustParse("2.6.0")},
+		{service: "adcm-2", repo: services.ADCMImage, version: semver.MustParse("2.7.0")},
+		{service: "adcm-3", repo: services.ADCMImage, version: semver.MustParse("2.5.1")},
+		{service: "adcm-4", repo: other, version: semver.MustParse("2.6.0")},
+	}
+
+	calls := map[string]int{}
+	versions := func(repo string) ([]semver.Version, error) {
+		calls[repo]++
+		return []semver.Version{semver.MustParse("2.6.0"), semver.MustParse("2.7.0")}, nil
+	}
+
+	got, err := selectUpgrade(images, semver.MustParse("2.7.0"), versions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []adcmImage{images[0], images[2], images[3]}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("selectUpgrade() = %v, want %v", got, want)
+	}
+	if want := map[string]int{services.ADCMImage: 1, other: 1}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("versions listed %v, want %v", calls, want)
+	}
+}
+
+func TestSelectUpgradeSameVersion(t *testing.T) {
+	images := []adcmImage{{service: "adcm", repo: services.ADCMImage, version: semver.MustParse("2.7.0")}}
+	versions := func(string) ([]semver.Version, error) {
+		t.Error("versions listed for an instance at the target version")
+		return nil, nil
+	}
+
+	got, err := selectUpgrade(images, semver.MustParse("2.7.0"), versions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("selectUpgrade() = %v, want none", got)
+	}
+}
+
+func TestSelectUpgradeErrors(t *testing.T) {
+	versions := func(string) ([]semver.Version, error) {
+		return []semver.Version{semver.MustParse("2.6.0"), semver.MustParse("2.7.0")}, nil
+	}
+
+	tests := []struct {
+		name    string
+		current string
+		target  string
+		want    string
+	}{
+		{"Downgrade", "2.7.0", "2.6.0", "downgrade from 2.7.0 to 2.6.0"},
+		{"PrereleaseDowngrade", "2.7.0", "2.7.0-rc.1", "downgrade"},
+		{"NotAvailable", "2.6.0", "2.8.0", "version 2.8.0 not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			images := []adcmImage{{service: "adcm", repo: services.ADCMImage, version: semver.MustParse(tt.current)}}
+			_, err := selectUpgrade(images, semver.MustParse(tt.target), versions)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("selectUpgrade() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,287 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/arenadata/adcm-installer/internal/services"
+	"github.com/arenadata/adcm-installer/pkg/compose"
+	"github.com/arenadata/adcm-installer/pkg/secrets"
+	"github.com/arenadata/adcm-installer/pkg/vault/unseal"
+	"github.com/arenadata/adcm-installer/pkg/vault/unseal/runner"
+
+	"github.com/AlecAivazis/survey/v2"
+	composeTypes "github.com/compose-spec/compose-go/v2/types"
+	"github.com/spf13/cobra"
+)
+
+var vaultCmd = &cobra.Command{
+	Use:   "vault",
+	Short: "Manage the Vault of the installation",
+	Long: `Runs Vault operations in the containers of the installation with the root token
+stored in x-secrets.`,
+}
+
+func init() {
+	rootCmd.AddCommand(vaultCmd)
+}
+
+// vaultProject is the running Vault of the project.
+type vaultProject struct {
+	prj       *composeTypes.Project
+	comp      *compose.Compose
+	primary   string
+	container string
+	config    services.VaultConfigFile
+	initData  *unseal.VaultInitData
+}
+
+// readVaultProject reads the configuration file and finds a running Vault
+// container. Requests to a standby node are forwarded to the active one.
+func readVaultProject(cmd *cobra.Command) (*vaultProject, error) {
+	configFilePath, _ := cmd.Flags().GetString("file")
+	prj, err := readConfigFile(configFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := vaultServices(prj)
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("project %s has no Vault", prj.Name)
+	}
+
+	aes, err := encoder(cmd, prj)
+	if err != nil {
+		return nil, err
+	}
+
+	xSecrets, unMapped, err := secretsDecrypt(prj.Services, aes)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &vaultProject{prj: prj, primary: nodes[0]}
+	if err = json.Unmarshal([]byte(xSecrets[v.primary][services.ConfigJson]), &v.config); err != nil {
+		return nil, fmt.Errorf("vault config: %v", err)
+	}
+
+	unsealData, ok := unMapped[v.primary][services.VaultUnsealData]
+	if !ok {
+		return nil, fmt.Errorf("vault of %s is not initialized, run adi apply", prj.Name)
+	}
+	if err = json.Unmarshal([]byte(unsealData), &v.initData); err != nil {
+		return nil, fmt.Errorf("unmarshal unseal data failed: %v", err)
+	}
+
+	if v.comp, err = compose.NewComposeService(); err != nil {
+		return nil, err
+	}
+
+	containers := runningVaultContainers(cmd.Context(), v.comp, prj.Name)
+	for _, node := range nodes {
+		if name, ok := containers[node]; ok {
+			v.container = name
+			break
+		}
+	}
+	if len(v.container) == 0 {
+		return nil, fmt.Errorf("no running Vault container of %s", prj.Name)
+	}
+
+	return v, nil
+}
+
+// exec runs a shell script in the Vault container with the root token.
+func (v *vaultProject) exec(ctx context.Context, script string, stdin io.Reader, stdout io.Writer) error {
+	env := []string{"BAO_TOKEN=" + v.initData.RootToken}
+	return v.comp.ExecStream(ctx, v.container, env, []string{"/bin/sh", "-ec", script}, stdin, stdout)
+}
+
+func unsealViaFlags(cmd *cobra.Command) {
+	cmd.Flags().String("unseal-via", "", fmt.Sprintf(
+		"Run the Vault unseal commands via one of %v, the init vault-unseal-via value by default", runner.Backends))
+}
+
+// vaultInitParams returns the initialization parameters of the Vault of the
+// project.
+func vaultInitParams(prj *composeTypes.Project) services.VaultInit {
+	var params services.VaultInit
+	nodes := vaultServices(prj)
+	if len(nodes) == 0 {
+		return params
+	}
+	if ext, ok := prj.Services[nodes[0]].Extensions[services.VaultInitKey]; ok {
+		params = *ext.(*services.VaultInit)
+	}
+	return params
+}
+
+// vaultRunner returns the runner of the Vault node with the unseal backend of
+// the deployment. The host and api backends connect to the published port.
+func (d *deployment) vaultRunner(node, container string) (unseal.Runner, error) {
+	via := d.unsealVia
+	if len(via) == 0 {
+		via = vaultInitParams(d.prj).UnsealVia
+	}
+
+	target := runner.Target{Container: container}
+	if via == runner.ViaHost || via == runner.ViaApi {
+		var err error
+		if target.Address, err = d.vaultAddress(node); err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(target.Address, "https:") {
+			target.CACert = []byte(d.xSecrets[node][services.PemCa])
+			target.ServerName = d.prj.Services[node].Hostname
+		}
+	}
+
+	return runner.New(via, target)
+}
+
+// vaultAddress returns the URL of the published API of the Vault node.
+func (d *deployment) vaultAddress(node string) (string, error) {
+	var config services.VaultConfigFile
+	if err := json.Unmarshal([]byte(d.xSecrets[node][services.ConfigJson]), &config); err != nil {
+		return "", fmt.Errorf("vault config of %s: %v", node, err)
+	}
+
+	scheme := "https"
+	for _, l := range config.Listener {
+		if tcp, ok := l["tcp"].(map[string]any); ok && tcp["tls_disable"] == true {
+			scheme = "http"
+		}
+	}
+
+	for _, p := range d.prj.Services[node].Ports {
+		if p.Target != uint32(services.VaultPublishPort) || len(p.Published) == 0 {
+			continue
+		}
+		hostIP := p.HostIP
+		if len(hostIP) == 0 || hostIP == "0.0.0.0" || hostIP == "::" {
+			hostIP = "127.0.0.1"
+		}
+		return fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(hostIP, p.Published)), nil
+	}
+
+	return "", fmt.Errorf("vault node %s does not publish port %d", node, services.VaultPublishPort)
+}
+
+func unsealIdentityFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArray("unseal-identity", nil,
+		"Private age or ssh key file of a Vault unseal key share holder, may be repeated")
+}
+
+// unsealIdentities reads the private keys of the unseal key share holders.
+func unsealIdentities(cmd *cobra.Command) ([]*secrets.AgeCrypt, error) {
+	files, _ := cmd.Flags().GetStringArray("unseal-identity")
+
+	var out []*secrets.AgeCrypt
+	for _, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		id, err := secrets.ParseAgeIdentity(b, func() (string, error) {
+			var pass string
+			err := survey.AskOne(&survey.Password{Message: fmt.Sprintf("Passphrase for %s:", file)}, &pass,
+				survey.WithValidator(survey.Required))
+			return pass, err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		out = append(out, id)
+	}
+
+	return out, nil
+}
+
+// encryptKeyShares encrypts every unseal key for its own recipient. The
+// result is keyed by the un-mapped x-secrets keys of the shares.
+func encryptKeyShares(aes secrets.Secrets, svcName string, keys, recipients []string) (map[string]string, error) {
+	if len(keys) != len(recipients) {
+		return nil, fmt.Errorf("vault returned %d unseal keys for %d recipients", len(keys), len(recipients))
+	}
+
+	out := make(map[string]string, len(keys))
+	for i, key := range keys {
+		name := fmt.Sprintf("%s-%d", services.VaultUnsealShare, i+1)
+
+		v, err := secrets.EncryptForRecipients(key, recipients[i])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		if aes != nil {
+			if v, err = aes.EncryptValue(v, svcName, name); err != nil {
+				return nil, fmt.Errorf("%s: %v", name, err)
+			}
+		}
+		out[name] = v
+	}
+
+	return out, nil
+}
+
+// decryptKeyShares decrypts the unseal key shares with the identities until
+// the threshold is reached.
+func decryptKeyShares(aes secrets.Secrets, svcName string, unMapped map[string]any, ids []*secrets.AgeCrypt, threshold int) ([]string, error) {
+	var names []string
+	for k := range unMapped {
+		if strings.HasPrefix(k, services.VaultUnsealShare+"-") {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+
+	var keys []string
+	for _, name := range names {
+		if len(keys) >= threshold {
+			break
+		}
+
+		v, _ := unMapped[name].(string)
+		if aes != nil {
+			var err error
+			if v, err = aes.DecryptValue(v, svcName, name); err != nil {
+				return nil, fmt.Errorf("%s: %v", name, err)
+			}
+		}
+
+		for _, id := range ids {
+			if key, err := id.Decrypt(v); err == nil {
+				keys = append(keys, key)
+				break
+			}
+		}
+	}
+
+	if len(keys) < threshold {
+		return nil, fmt.Errorf("vault is sealed, %d of %d unseal key shares decrypted: "+
+			"pass the private keys of the share holders with --unseal-identity", len(keys), threshold)
+	}
+
+	return keys, nil
+}
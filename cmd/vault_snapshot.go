@@ -0,0 +1,44 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// vaultSnapshotPath is the temporary snapshot file in the Vault container.
+const vaultSnapshotPath = "/tmp/adi-vault.snap"
+
+var vaultSnapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Save and restore Vault raft snapshots",
+	Long: `Saves and restores snapshots of the Vault integrated storage. Only the raft
+storage (vault-storage: raft) supports snapshots, the postgresql storage is
+saved with the ADPG dump of adi backup.`,
+}
+
+func init() {
+	vaultCmd.AddCommand(vaultSnapshotCmd)
+}
+
+func (v *vaultProject) checkRaft() error {
+	if v.config.Storage.Raft == nil {
+		return fmt.Errorf("snapshots require the raft storage of Vault")
+	}
+	return nil
+}
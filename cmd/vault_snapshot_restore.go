@@ -0,0 +1,77 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cmd
+
+import (
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var vaultSnapshotRestoreCmd = &cobra.Command{
+	Use:   "restore <file>",
+	Short: "Restore the Vault raft storage from a snapshot",
+	Long: `Restores the raft storage from a snapshot saved by adi vault snapshot save. The
+whole storage is replaced, the changes made after the snapshot are lost.
+- --age-key takes the value of the private key in clear text. Has priority over
+            --age-key-file
+- --age-key-file takes the value of the path to the file with the private key
+- --file specifies the path to the configuration file
+- --force restores a snapshot of another Vault cluster. The unseal keys of that
+          cluster are required to unseal Vault afterwards`,
+	PreRunE: cobra.ExactArgs(1),
+	Run:     vaultSnapshotRestore,
+}
+
+func init() {
+	vaultSnapshotCmd.AddCommand(vaultSnapshotRestoreCmd)
+
+	ageKeyFlags(vaultSnapshotRestoreCmd, "age-key", ageKeyFileName)
+	configFileFlags(vaultSnapshotRestoreCmd)
+	vaultSnapshotRestoreCmd.Flags().Bool("force", false, "Restore a snapshot of another Vault cluster")
+}
+
+func vaultSnapshotRestore(cmd *cobra.Command, args []string) {
+	logger := log.WithField("command", "vault-snapshot-restore")
+
+	v, err := readVaultProject(cmd)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if err = v.checkRaft(); err != nil {
+		logger.Fatal(err)
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		logger.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+
+	restore := "bao operator raft snapshot restore "
+	if getBool(cmd, "force") {
+		restore += "-force "
+	}
+	script := "trap 'rm -f " + vaultSnapshotPath + "' EXIT\n" +
+		"cat > " + vaultSnapshotPath + "\n" +
+		restore + vaultSnapshotPath
+	if err = v.exec(cmd.Context(), script, f, nil); err != nil {
+		logger.Fatalf("snapshot restore failed: %v", err)
+	}
+
+	logger.Infof("Vault restored from %s", args[0])
+}
@@ -0,0 +1,72 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cmd
+
+import (
+	"io"
+
+	"github.com/arenadata/adcm-installer/pkg/utils"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var vaultSnapshotSaveCmd = &cobra.Command{
+	Use:   "save <file>",
+	Short: "Save a snapshot of the Vault raft storage",
+	Long: `Takes a snapshot of the raft storage through a running Vault node and writes it
+to the file. The snapshot is encrypted by the Vault barrier, the unseal keys of
+the installation are required to use it.
+- --age-key takes the value of the private key in clear text. Has priority over
+            --age-key-file
+- --age-key-file takes the value of the path to the file with the private key
+- --file specifies the path to the configuration file`,
+	PreRunE: cobra.ExactArgs(1),
+	Run:     vaultSnapshotSave,
+}
+
+func init() {
+	vaultSnapshotCmd.AddCommand(vaultSnapshotSaveCmd)
+
+	ageKeyFlags(vaultSnapshotSaveCmd, "age-key", ageKeyFileName)
+	configFileFlags(vaultSnapshotSaveCmd)
+}
+
+func vaultSnapshotSave(cmd *cobra.Command, args []string) {
+	logger := log.WithField("command", "vault-snapshot-save")
+	outputPath := args[0]
+
+	v, err := readVaultProject(cmd)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if err = v.checkRaft(); err != nil {
+		logger.Fatal(err)
+	}
+
+	script := "trap 'rm -f " + vaultSnapshotPath + "' EXIT\n" +
+		"bao operator raft snapshot save " + vaultSnapshotPath + " >&2\n" +
+		"cat " + vaultSnapshotPath
+	// the temporary file is removed before the exit on a failure
+	err = utils.WriteAtomic(outputPath, 0600, func(w io.Writer) error {
+		return v.exec(cmd.Context(), script, nil, w)
+	})
+	if err != nil {
+		logger.Fatalf("snapshot save failed: %v", err)
+	}
+
+	logger.Infof("Vault snapshot saved to %s", outputPath)
+}
@@ -0,0 +1,95 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cmd
+
+import (
+	"crypto/rand"
+	"reflect"
+	"testing"
+
+	"github.com/arenadata/adcm-installer/pkg/secrets"
+)
+
+func newTestIdentities(t *testing.T, n int) ([]*secrets.AgeCrypt, []string) {
+	var ids []*secrets.AgeCrypt
+	var recipients []string
+	for i := 0; i < n; i++ {
+		id, err := secrets.NewAgeCrypt()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+		recipients = append(recipients, id.Recipient())
+	}
+	return ids, recipients
+}
+
+func TestKeyShares(t *testing.T) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	aes, err := secrets.NewAesCrypt(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := []string{"key-1", "key-2", "key-3"}
+	ids, recipients := newTestIdentities(t, len(keys))
+	others, _ := newTestIdentities(t, 1)
+
+	tests := []struct {
+		name      string
+		aes       secrets.Secrets
+		ids       []*secrets.AgeCrypt
+		threshold int
+		want      []string
+		wantError bool
+	}{
+		{"RoundTrip", aes, ids, 3, keys, false},
+		{"RoundTripNoEncryption", nil, ids, 3, keys, false},
+		{"Threshold", aes, ids[1:], 2, keys[1:], false},
+		{"ThresholdNotReached", aes, ids[:1], 2, nil, true},
+		{"WrongIdentity", aes, others, 1, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shares, err := encryptKeyShares(tt.aes, "vault", keys, recipients)
+			if err != nil {
+				t.Fatal(err)
+			}
+			unMapped := make(map[string]any, len(shares))
+			for k, v := range shares {
+				unMapped[k] = v
+			}
+
+			got, err := decryptKeyShares(tt.aes, "vault", unMapped, tt.ids, tt.threshold)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("error = %v, wantError %v", err, tt.wantError)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncryptKeySharesRecipientsMismatch(t *testing.T) {
+	_, recipients := newTestIdentities(t, 2)
+	if _, err := encryptKeyShares(nil, "vault", []string{"key-1"}, recipients); err == nil {
+		t.Error("shares encrypted for more recipients than keys")
+	}
+}
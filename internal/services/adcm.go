@@ -17,10 +17,12 @@ package services
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"path"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/arenadata/adcm-installer/internal/services/helpers"
 	"github.com/arenadata/adcm-installer/pkg/compose"
@@ -28,6 +30,38 @@ import (
 	"github.com/arenadata/adcm-installer/pkg/utils"
 
 	"github.com/AlecAivazis/survey/v2"
+	composeTypes "github.com/compose-spec/compose-go/v2/types"
+)
+
+const (
+	adcmHTTPEnabled  = "enabled"
+	adcmHTTPRedirect = "redirect to HTTPS"
+	adcmHTTPDisabled = "disabled"
+
+	// adcmHealthPath is the ADCM API root. It requires authentication, so the
+	// health checks rely on any status but a server error: 401 or 403 means
+	// the API has started and its database is ready, while the nginx of the
+	// container answers 502 until then.
+	adcmHealthPath = "/api/v2/"
+	// adcmHealthyStatus matches the HTTP status of a started ADCM API.
+	adcmHealthyStatus = "[234][0-9][0-9]"
+
+	// redirectConf is the nginx configuration of the HTTPS redirect. The $ of
+	// nginx variables is escaped from compose interpolation.
+	redirectConf = `pid /tmp/nginx.pid;
+events {}
+http {
+  access_log off;
+  client_body_temp_path /tmp/client_body;
+  proxy_temp_path /tmp/proxy;
+  fastcgi_temp_path /tmp/fastcgi;
+  uwsgi_temp_path /tmp/uwsgi;
+  scgi_temp_path /tmp/scgi;
+  server {
+    listen %d;
+    return 301 https://$$host:%d$$request_uri;
+  }
+}`
 )
 
 type AdcmConfig struct {
@@ -43,6 +77,8 @@ type AdcmConfig struct {
 	DBSSLKeyFile   string `yaml:"adcm-db-ssl-key-file"`
 	SSLKeyFile     string `yaml:"adcm-ssl-key-file"`
 	SSLCertFile    string `yaml:"adcm-ssl-cert-file"`
+	HTTPSRedirect  bool   `yaml:"adcm-https-redirect"`
+	DisableHTTP    bool   `yaml:"adcm-disable-http"`
 	Image          string `yaml:"adcm-image"`
 	Tag            string `yaml:"adcm-tag"`
 	PublishPort    uint16 `yaml:"adcm-publish-port"`
@@ -74,13 +110,18 @@ func (prj *Project) adcm(name string) {
 		config.Volume = hostname
 	}
 
+	// behind the proxy ADCM is not published and HTTPS is served by the proxy
+	proxy := prj.config.Proxy.enable
+
 	if prj.interactive {
 		checkErr(readValue(&config.Image, &prompt{msg: fmt.Sprintf("%s: ADCM image:", name), def: config.Image}))
 		checkErr(readValue(&config.Tag, &prompt{msg: fmt.Sprintf("%s: ADCM image tag:", name), def: config.Tag}))
 
-		adcmPublishPortDefault := strconv.Itoa(int(config.PublishPort))
-		checkErr(readValue(&config.PublishPort,
-			&prompt{msg: fmt.Sprintf("%s: ADCM publish port:", name), def: adcmPublishPortDefault}))
+		if !proxy {
+			adcmPublishPortDefault := strconv.Itoa(int(config.PublishPort))
+			checkErr(readValue(&config.PublishPort,
+				&prompt{msg: fmt.Sprintf("%s: ADCM publish port:", name), def: adcmPublishPortDefault}))
+		}
 	}
 
 	managedADPG := prj.config.Adpg.enable
@@ -121,36 +162,23 @@ func (prj *Project) adcm(name string) {
 	}
 
 	if prj.interactive {
-		checkErr(readValue(&config.Url, &prompt{msg: fmt.Sprintf("%s: ADCM url", name), def: config.Url}))
 		checkErr(readValue(&config.Volume,
 			&prompt{msg: fmt.Sprintf("%s: ADCM volume name or path:", name), def: config.Volume}))
 
-		p := &prompt{msg: fmt.Sprintf("%s: ADCM SSL Private Key file path:", name),
-			help: "Leave blank if you do not enable HTTPS"}
-		checkErr(readValue(&config.SSLKeyFile, p, fileExists))
-		if len(config.SSLKeyFile) > 0 {
-			checkErr(readValue(&config.SSLCertFile,
-				&prompt{msg: fmt.Sprintf("%s: ADCM SSL Certificate file path:", name)}, fileExists))
+		if !proxy {
+			prj.readHTTPSConfig(name, &config)
+		}
+	}
 
-			sslPort := strconv.Itoa(int(config.PublishSSLPort))
-			checkErr(readValue(&config.PublishSSLPort,
-				&prompt{msg: fmt.Sprintf("%s: ADCM publish SSL port:", name), def: sslPort}))
-
-			prj.AppendHelpers(
-				helpers.Secrets(name,
-					helpers.Secret{
-						Source:   PemKey,
-						Target:   path.Join(ADCMMountPath, "conf/ssl/key.pem"),
-						FileMode: 0o400,
-					},
-					helpers.Secret{
-						Source:   PemCert,
-						Target:   path.Join(ADCMMountPath, "conf/ssl/cert.pem"),
-						FileMode: 0o440,
-					},
-				),
-				helpers.PublishPort(name, config.PublishSSLPort, ADCMPublishSSLPort),
-			)
+	if proxy {
+		config.Url = prj.config.Proxy.url
+	} else {
+		if prj.checkHTTPSConfig(name, &config) {
+			prj.adcmHTTPS(name, config.PublishSSLPort)
+		}
+
+		if prj.interactive {
+			checkErr(readValue(&config.Url, &prompt{msg: fmt.Sprintf("%s: ADCM url", name), def: config.Url}))
 		}
 	}
 
@@ -162,8 +190,9 @@ func (prj *Project) adcm(name string) {
 		prj.AppendHelpers(
 			helpers.DependsOn(name,
 				helpers.Depended{
-					Service:  AdpgName,
-					Required: true,
+					Service:   AdpgName,
+					Condition: composeTypes.ServiceConditionHealthy,
+					Required:  true,
 				}),
 		)
 	} else {
@@ -181,74 +210,36 @@ func (prj *Project) adcm(name string) {
 		PgDbPass: config.DBPassword,
 	}
 
-	if config.DBSSLMode != pgSslModeDisable {
-		sslOpts := types.DbSSLOptions{SSLMode: config.DBSSLMode}
-
-		if len(config.DBSSLCaFile) > 0 {
-			b, err := os.ReadFile(config.DBSSLCaFile)
-			checkErr(err)
-			xsecretsData[PgSslCaKey] = string(b)
-
-			target := path.Join(helpers.SecretsPath, PgSslCaKey)
-			sslOpts.SSLRootCert = target
-			prj.AppendHelpers(
-				helpers.Secrets(name, helpers.Secret{
-					Source:   PgSslCaKey,
-					Target:   target,
-					FileMode: 0o440,
-				}),
-			)
-		}
-		if len(config.DBSSLCertFile) > 0 {
-			b, err := os.ReadFile(config.DBSSLCertFile)
-			checkErr(err)
-			xsecretsData[PgSslCertKey] = string(b)
-
-			target := path.Join(helpers.SecretsPath, PgSslCertKey)
-			sslOpts.SSLCert = target
-			prj.AppendHelpers(
-				helpers.Secrets(name, helpers.Secret{
-					Source:   PgSslCertKey,
-					Target:   target,
-					FileMode: 0o440,
-				}),
-			)
+	if prj.ca != nil {
+		if !proxy {
+			xsecretsData[PemCert], xsecretsData[PemKey] = prj.serverCert(name, hostIPs(config.ip)...)
 		}
-		if len(config.DBSSLKeyFile) > 0 {
-			b, err := os.ReadFile(config.DBSSLKeyFile)
-			checkErr(err)
-			xsecretsData[PgSslKeyKey] = string(b)
-			target := path.Join(helpers.SecretsPath, PgSslKeyKey)
-			sslOpts.SSLKey = target
-			prj.AppendHelpers(
-				helpers.Secrets(name, helpers.Secret{
-					Source:   PgSslKeyKey,
-					Target:   target,
-					FileMode: 0o400,
-				}),
-			)
+
+		if managedADPG {
+			config.DBSSLMode = pgSslModeVerifyFull
+			xsecretsData[PgSslCaKey] = prj.ca.CertPEM()
+			xsecretsData[PgSslCertKey], xsecretsData[PgSslKeyKey] = prj.clientCert(config.DBUser)
 		}
+	}
+
+	if config.DBSSLMode != pgSslModeDisable {
+		sslOpts := types.DbSSLOptions{SSLMode: config.DBSSLMode}
+		sslOpts.SSLRootCert, sslOpts.SSLCert, sslOpts.SSLKey = prj.dbSSLSecrets(name,
+			config.DBSSLCaFile, config.DBSSLCertFile, config.DBSSLKeyFile, xsecretsData)
 
 		optStr := sslOpts.String()
 		prj.AppendHelpers(helpers.Environment(name, helpers.Env{Name: "DB_OPTIONS", Value: &optStr}))
 	}
 
-	if len(config.SSLKeyFile) > 0 {
-		b, err := os.ReadFile(config.SSLKeyFile)
-		checkErr(err)
-		xsecretsData[PemKey] = string(b)
-	}
-	if len(config.SSLCertFile) > 0 {
-		b, err := os.ReadFile(config.SSLCertFile)
-		checkErr(err)
-		xsecretsData[PemCert] = string(b)
+	if !proxy {
+		readHTTPSFiles(&config, xsecretsData)
 	}
 
 	xsecretsDataEncrypted := xsecretsData
 	if prj.crypt != nil {
 		var err error
 		for k, v := range xsecretsData {
-			v, err = prj.crypt.EncryptValue(v)
+			v, err = prj.crypt.EncryptValue(v, name, k)
 			checkErr(err)
 			xsecretsDataEncrypted[k] = v
 		}
@@ -262,9 +253,131 @@ func (prj *Project) adcm(name string) {
 		helpers.Environment(name, helpers.Env{Name: "DEFAULT_ADCM_URL", Value: &config.Url}),
 		helpers.Extension(name, XSecretsKey, &XSecrets{Data: xsecretsDataEncrypted}),
 		helpers.Volumes(name, config.Volume+":"+ADCMMountPath),
+		helpers.HealthCheck(name, helpers.HealthCheckConfig{
+			// wget fails on 4xx, so the status line is checked instead
+			Cmd: []string{"CMD-SHELL", fmt.Sprintf("wget -S -O /dev/null http://127.0.0.1:%d%s 2>&1 | grep -q 'HTTP/[0-9.]* %s'",
+				ADCMPublishPort, adcmHealthPath, adcmHealthyStatus)},
+			Interval:      10 * time.Second,
+			Timeout:       5 * time.Second,
+			StartPeriod:   3 * time.Minute,
+			StartInterval: 5 * time.Second,
+			Retries:       3,
+		}),
 	)
 
-	if config.PublishPort > 0 {
+	switch {
+	case proxy, config.PublishPort == 0 || config.DisableHTTP:
+	case config.HTTPSRedirect:
+		prj.httpsRedirect(name, config.PublishPort, config.PublishSSLPort)
+	default:
 		prj.AppendHelpers(helpers.PublishPort(name, config.PublishPort, ADCMPublishPort))
 	}
 }
+
+// readHTTPSConfig prompts for the certificate, unless it is issued by the
+// project CA, and for the handling of the HTTP port.
+func (prj *Project) readHTTPSConfig(name string, config *AdcmConfig) {
+	if prj.ca == nil {
+		p := &prompt{msg: fmt.Sprintf("%s: ADCM SSL Private Key file path:", name),
+			help: "Leave blank if you do not enable HTTPS"}
+		checkErr(readValue(&config.SSLKeyFile, p, fileExists))
+		if len(config.SSLKeyFile) > 0 {
+			checkErr(readValue(&config.SSLCertFile,
+				&prompt{msg: fmt.Sprintf("%s: ADCM SSL Certificate file path:", name)}, fileExists))
+		}
+	}
+
+	if len(config.SSLKeyFile) > 0 || prj.ca != nil {
+		sslPort := strconv.Itoa(int(config.PublishSSLPort))
+		checkErr(readValue(&config.PublishSSLPort,
+			&prompt{msg: fmt.Sprintf("%s: ADCM publish SSL port:", name), def: sslPort}))
+
+		httpMode := adcmHTTPEnabled
+		checkErr(readValue(&httpMode, &prompt{msg: fmt.Sprintf("%s: ADCM HTTP port:", name), def: httpMode,
+			opts: []string{adcmHTTPEnabled, adcmHTTPRedirect, adcmHTTPDisabled}}))
+		config.HTTPSRedirect = httpMode == adcmHTTPRedirect
+		config.DisableHTTP = httpMode == adcmHTTPDisabled
+	}
+}
+
+// checkHTTPSConfig validates the HTTPS settings and points the default url to
+// the HTTPS port if HTTP is not served. Reports whether HTTPS is enabled.
+func (prj *Project) checkHTTPSConfig(name string, config *AdcmConfig) bool {
+	tlsEnabled := len(config.SSLKeyFile) > 0 || prj.ca != nil
+	if tlsEnabled {
+		if prj.ca == nil && len(config.SSLCertFile) == 0 {
+			checkErr(fmt.Errorf("%s: adcm-ssl-cert-file is required with adcm-ssl-key-file", name))
+		}
+	} else if config.HTTPSRedirect || config.DisableHTTP {
+		checkErr(fmt.Errorf("%s: adcm-https-redirect and adcm-disable-http require TLS", name))
+	}
+	if config.HTTPSRedirect && config.DisableHTTP {
+		checkErr(fmt.Errorf("%s: adcm-https-redirect and adcm-disable-http are mutually exclusive", name))
+	}
+
+	if (config.HTTPSRedirect || config.DisableHTTP) &&
+		config.Url == fmt.Sprintf("http://%s:%d", config.ip, config.PublishPort) {
+		config.Url = fmt.Sprintf("https://%s:%d", config.ip, config.PublishSSLPort)
+	}
+
+	return tlsEnabled
+}
+
+// readHTTPSFiles reads the certificate files into data, replacing the issued
+// ones.
+func readHTTPSFiles(config *AdcmConfig, data map[string]string) {
+	if len(config.SSLKeyFile) > 0 {
+		b, err := os.ReadFile(config.SSLKeyFile)
+		checkErr(err)
+		data[PemKey] = string(b)
+	}
+	if len(config.SSLCertFile) > 0 {
+		b, err := os.ReadFile(config.SSLCertFile)
+		checkErr(err)
+		data[PemCert] = string(b)
+	}
+}
+
+func hostIPs(ip string) []net.IP {
+	if parsed := net.ParseIP(ip); parsed != nil {
+		return []net.IP{parsed}
+	}
+	return nil
+}
+
+// adcmHTTPS mounts the certificate to ADCM and publishes the HTTPS port.
+func (prj *Project) adcmHTTPS(name string, publishSSLPort uint16) {
+	prj.AppendHelpers(
+		helpers.Secrets(name,
+			helpers.Secret{
+				Source:   PemKey,
+				Target:   path.Join(ADCMMountPath, "conf/ssl/key.pem"),
+				FileMode: 0o400,
+			},
+			helpers.Secret{
+				Source:   PemCert,
+				Target:   path.Join(ADCMMountPath, "conf/ssl/cert.pem"),
+				FileMode: 0o440,
+			},
+		),
+		helpers.PublishPort(name, publishSSLPort, ADCMPublishSSLPort),
+	)
+}
+
+// httpsRedirect adds the service answering on the HTTP port of ADCM with a
+// redirect to its HTTPS port.
+func (prj *Project) httpsRedirect(adcmName string, publishPort, publishSSLPort uint16) {
+	name := adcmName + "-redirect"
+	addService(name, prj.prj)
+
+	conf := fmt.Sprintf(redirectConf, NginxPort, publishSSLPort)
+	script := fmt.Sprintf("echo '%s' > /tmp/nginx.conf && exec nginx -e stderr -c /tmp/nginx.conf -g 'daemon off;'", conf)
+
+	prj.AppendHelpers(
+		helpers.Hostname(name, prj.hostname(name)),
+		helpers.Image(name, NginxImage+":"+NginxTag),
+		helpers.Entrypoint(name, "/bin/sh", "-c", script),
+		helpers.Labels(name, map[string]string{compose.ADAppTypeLabelKey: RedirectName}),
+		helpers.PublishPort(name, publishPort, NginxPort),
+	)
+}
@@ -16,6 +16,7 @@
 package services
 
 import (
+	"path"
 	"strconv"
 	"time"
 
@@ -64,21 +65,29 @@ func (prj *Project) adpg() {
 		config.Password = utils.GenerateRandomString(16)
 	}
 
-	passwd := config.Password
-	if prj.crypt != nil {
-		var err error
-		passwd, err = prj.crypt.EncryptValue(config.Password)
-		checkErr(err)
+	xsecretsData := map[string]string{
+		"password": config.Password,
+	}
+
+	if prj.ca != nil {
+		xsecretsData[PemCa] = prj.ca.CertPEM()
+		xsecretsData[PemCert], xsecretsData[PemKey] = prj.serverCert(name)
+		prj.adpgTLS(name)
 	}
 
-	xsecretsDataEncrypted := map[string]string{
-		"password": passwd,
+	if prj.crypt != nil {
+		var err error
+		for k, v := range xsecretsData {
+			v, err = prj.crypt.EncryptValue(v, name, k)
+			checkErr(err)
+			xsecretsData[k] = v
+		}
 	}
 
 	prj.AppendHelpers(
 		helpers.Hostname(name, hostname),
 		helpers.Image(name, config.Image+":"+config.Tag),
-		helpers.Extension(name, XSecretsKey, &XSecrets{Data: xsecretsDataEncrypted}),
+		helpers.Extension(name, XSecretsKey, &XSecrets{Data: xsecretsData}),
 		helpers.Labels(name, map[string]string{compose.ADAppTypeLabelKey: AdpgName}),
 		helpers.HealthCheck(name, helpers.HealthCheckConfig{
 			Cmd:      []string{"CMD-SHELL", "pg-entrypoint isready postgres"},
@@ -93,3 +102,26 @@ func (prj *Project) adpg() {
 		prj.AppendHelpers(helpers.PublishPort(name, config.PublishPort, ADPGPublishPort))
 	}
 }
+
+// adpgTLS mounts the certificates to ADPG and enables SSL. Clients presenting
+// a certificate are verified by the CA.
+func (prj *Project) adpgTLS(name string) {
+	keyTarget := path.Join(helpers.SecretsPath, PemKey)
+	certTarget := path.Join(helpers.SecretsPath, PemCert)
+	caTarget := path.Join(helpers.SecretsPath, PemCa)
+
+	prj.AppendHelpers(
+		helpers.Secrets(name,
+			helpers.Secret{Source: PemKey, Target: keyTarget, FileMode: 0o400},
+			helpers.Secret{Source: PemCert, Target: certTarget, FileMode: 0o440},
+			helpers.Secret{Source: PemCa, Target: caTarget, FileMode: 0o440},
+		),
+		helpers.Command(name, []string{
+			"postgres",
+			"-c", "ssl=on",
+			"-c", "ssl_cert_file=" + certTarget,
+			"-c", "ssl_key_file=" + keyTarget,
+			"-c", "ssl_ca_file=" + caTarget,
+		}),
+	)
+}
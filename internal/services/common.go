@@ -20,13 +20,14 @@ import (
 	"strings"
 
 	"github.com/arenadata/adcm-installer/assets"
-	"github.com/arenadata/adcm-installer/internal/services/helpers"
+	"github.com/arenadata/adcm-installer/pkg/compose"
 
 	composeTypes "github.com/compose-spec/compose-go/v2/types"
 	"github.com/docker/compose/v2/pkg/api"
-	composeUtils "github.com/docker/compose/v2/pkg/utils"
 )
 
+// ChownContainer adds the init job changing the owner of the volumes of the
+// service. It is run on every apply.
 func ChownContainer(prj *composeTypes.Project, svc composeTypes.ServiceConfig) string {
 	var mounts []string
 	for _, mnt := range svc.Volumes {
@@ -42,8 +43,8 @@ func ChownContainer(prj *composeTypes.Project, svc composeTypes.ServiceConfig) s
 			"-cex",
 			fmt.Sprintf("chown -v %s %s", svc.User, strings.Join(mounts, " ")),
 		},
-		Volumes:  svc.Volumes,
-		Profiles: []string{"chown", InitContainerProfile},
+		Volumes: svc.Volumes,
+		Labels:  composeTypes.Labels{compose.ADJobLabelKey: ""},
 	}
 
 	setCustomLabels(prj, &newSvc)
@@ -51,6 +52,8 @@ func ChownContainer(prj *composeTypes.Project, svc composeTypes.ServiceConfig) s
 	return newSvc.Name
 }
 
+// InitContainer adds the init job of the service. It is skipped while the
+// marker in the first volume of the service matches its configuration.
 func InitContainer(prj *composeTypes.Project, svc composeTypes.ServiceConfig) string {
 	newSvc := composeTypes.ServiceConfig{
 		Name:        "init-" + svc.Name,
@@ -59,7 +62,7 @@ func InitContainer(prj *composeTypes.Project, svc composeTypes.ServiceConfig) st
 		Volumes:     svc.Volumes,
 		Secrets:     svc.Secrets,
 		Environment: composeTypes.MappingWithEquals{},
-		Profiles:    []string{InitContainerProfile},
+		Labels:      composeTypes.Labels{compose.ADJobLabelKey: ".adi-init-" + svc.Name},
 	}
 
 	setCustomLabels(prj, &newSvc)
@@ -67,35 +70,6 @@ func InitContainer(prj *composeTypes.Project, svc composeTypes.ServiceConfig) st
 	return newSvc.Name
 }
 
-func PauseContainer(prj *composeTypes.Project) {
-	var depends []helpers.Depended
-	for _, svc := range prj.Services {
-		if composeUtils.StringContains(svc.Profiles, InitContainerProfile) {
-			depends = append(depends,
-				helpers.Depended{Service: svc.Name, Condition: composeTypes.ServiceConditionCompletedSuccessfully})
-		}
-	}
-
-	if len(depends) == 0 {
-		return
-	}
-
-	newSvc := composeTypes.ServiceConfig{
-		Name:       PauseName,
-		Image:      assets.ImageName,
-		Command:    []string{"sleep", "120"},
-		StopSignal: "SIGKILL",
-		Profiles:   []string{InitContainerProfile},
-	}
-
-	setCustomLabels(prj, &newSvc)
-	prj.Services[PauseName] = newSvc
-
-	servicesModHelpers := helpers.NewModHelpers()
-	servicesModHelpers = append(servicesModHelpers, helpers.DependsOn(PauseName, depends...))
-	_ = servicesModHelpers.Apply(prj)
-}
-
 func setCustomLabels(prj *composeTypes.Project, svc *composeTypes.ServiceConfig) {
 	svc.CustomLabels = make(composeTypes.Labels)
 	svc.CustomLabels.
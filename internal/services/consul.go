@@ -16,19 +16,45 @@
 package services
 
 import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
 	"strconv"
+	"time"
 
 	"github.com/arenadata/adcm-installer/internal/services/helpers"
 	"github.com/arenadata/adcm-installer/pkg/compose"
+	"github.com/arenadata/adcm-installer/pkg/utils"
+
+	"github.com/google/uuid"
 )
 
+const consulServerPort = 8300
+
 type ConsulConfig struct {
 	enable bool
 
+	Count       uint8  `yaml:"consul-count"`
+	Datacenter  string `yaml:"consul-datacenter"`
 	Image       string `yaml:"consul-image"`
 	Tag         string `yaml:"consul-tag"`
 	PublishPort uint16 `yaml:"consul-publish-port"`
 	Volume      string `yaml:"consul-volume"`
+	SSLCaFile   string `yaml:"consul-ssl-ca-file"`
+	SSLCertFile string `yaml:"consul-ssl-cert-file"`
+	SSLKeyFile  string `yaml:"consul-ssl-key-file"`
+}
+
+// consulCluster holds the values shared by the Consul servers.
+type consulCluster struct {
+	config    ConsulConfig
+	nodes     []string
+	gossipKey string
+	aclToken  string
+	tls       bool
 }
 
 func (prj *Project) consul() {
@@ -37,31 +63,200 @@ func (prj *Project) consul() {
 		return
 	}
 
-	name := ConsulName
-	addService(name, prj.prj)
-
-	hostname := prj.hostname(name)
-	if len(config.Volume) == 0 {
-		config.Volume = hostname
-	}
-
 	if prj.interactive {
 		checkErr(readValue(&config.Image, &prompt{msg: "Consul image", def: config.Image}))
 		checkErr(readValue(&config.Tag, &prompt{msg: "Consul image tag", def: config.Tag}))
 
+		count := strconv.Itoa(int(config.Count))
+		checkErr(readValue(&count, &prompt{msg: "Number of Consul servers", def: count, opts: []string{"1", "3"}}))
+		n, err := strconv.ParseUint(count, 10, 8)
+		checkErr(err)
+		config.Count = uint8(n)
+
 		portStr := strconv.Itoa(int(config.PublishPort))
 		checkErr(readValue(&config.PublishPort, &prompt{msg: "Consul publish port", def: portStr}))
-		checkErr(readValue(&config.Volume, &prompt{msg: "Consul volume name or path", def: config.Volume}))
+		checkErr(readValue(&config.Volume, &prompt{msg: "Consul volume name or path",
+			help: "Leave blank to use a volume named after the server"}))
+
+		if prj.ca == nil {
+			p := &prompt{msg: "Consul SSL Private Key file path:", help: "Leave blank if you do not enable TLS"}
+			checkErr(readValue(&config.SSLKeyFile, p, fileExists))
+			if len(config.SSLKeyFile) > 0 {
+				checkErr(readValue(&config.SSLCertFile, &prompt{msg: "Consul SSL Certificate file path:"}, fileExists))
+				checkErr(readValue(&config.SSLCaFile, &prompt{msg: "Consul SSL CA file path:"}, fileExists))
+			}
+		}
+	}
+
+	if config.Count != 1 && config.Count != 3 {
+		checkErr(fmt.Errorf("consul-count must be 1 or 3, got %d", config.Count))
+	}
+
+	cluster := &consulCluster{
+		config:   config,
+		nodes:    []string{ConsulName},
+		aclToken: uuid.NewString(),
+		tls:      prj.ca != nil || len(config.SSLKeyFile) > 0,
+	}
+	if cluster.tls && prj.ca == nil && (len(config.SSLCertFile) == 0 || len(config.SSLCaFile) == 0) {
+		checkErr(fmt.Errorf("consul-ssl-cert-file and consul-ssl-ca-file are required with consul-ssl-key-file"))
+	}
+
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	checkErr(err)
+	cluster.gossipKey = base64.StdEncoding.EncodeToString(key)
+
+	if config.Count > 1 {
+		cluster.nodes = cluster.nodes[:0]
+		for i := uint8(1); i <= config.Count; i++ {
+			cluster.nodes = append(cluster.nodes, fmt.Sprintf("%s-%d", ConsulName, i))
+		}
+	}
+
+	for i, name := range cluster.nodes {
+		prj.consulServer(cluster, i, name)
+	}
+}
+
+// consulServer adds a Consul server with gossip encryption and ACLs enabled.
+// The ACL bootstrap token is also the agent token of the servers.
+func (prj *Project) consulServer(cluster *consulCluster, i int, name string) {
+	config := cluster.config
+	addService(name, prj.prj)
+
+	hostname := prj.hostname(name)
+	volume := hostname
+	if len(config.Volume) > 0 {
+		volume = config.Volume
+		if len(cluster.nodes) > 1 {
+			volume = fmt.Sprintf("%s-%d", config.Volume, i+1)
+		}
+	}
+
+	var retryJoin []string
+	for _, node := range cluster.nodes {
+		if node != name {
+			retryJoin = append(retryJoin, prj.hostname(node))
+		}
+	}
+
+	consulConfig := map[string]any{
+		"server":           true,
+		"bootstrap_expect": len(cluster.nodes),
+		"node_name":        hostname,
+		"datacenter":       config.Datacenter,
+		"data_dir":         ConsulDataMountPath,
+		"bind_addr":        "{{ GetPrivateIP }}",
+		"client_addr":      "0.0.0.0",
+		"encrypt":          cluster.gossipKey,
+		"ui_config":        map[string]any{"enabled": true},
+		"acl": map[string]any{
+			"enabled":        true,
+			"default_policy": "deny",
+			"tokens": map[string]string{
+				"initial_management": cluster.aclToken,
+				"agent":              cluster.aclToken,
+			},
+		},
+	}
+	if len(retryJoin) > 0 {
+		consulConfig["retry_join"] = retryJoin
+	}
+
+	xsecretsData := map[string]string{}
+	port := ConsulPublishPort
+	addr := fmt.Sprintf("http://127.0.0.1:%d", ConsulPublishPort)
+	var wgetArg string
+	if cluster.tls {
+		port = ConsulPublishSSLPort
+		addr = fmt.Sprintf("https://127.0.0.1:%d", ConsulPublishSSLPort)
+		wgetArg = " --no-check-certificate"
+
+		prj.consulTLS(cluster, name, consulConfig, xsecretsData)
+	}
+
+	b, err := json.Marshal(consulConfig)
+	checkErr(err)
+	xsecretsData[ConfigJson] = string(b)
+
+	if prj.crypt != nil {
+		for k, v := range xsecretsData {
+			v, err = prj.crypt.EncryptValue(v, name, k)
+			checkErr(err)
+			xsecretsData[k] = v
+		}
 	}
 
+	configTarget := path.Join(helpers.SecretsPath, ConfigJson)
+	healthCheckCommand := fmt.Sprintf("wget%s -q -O - %s/v1/status/leader | grep -q :%d",
+		wgetArg, addr, consulServerPort)
+
 	prj.AppendHelpers(
 		helpers.Hostname(name, hostname),
-		helpers.Command(name, []string{"agent", "-dev", "-bind=0.0.0.0"}),
+		helpers.Command(name, []string{"agent", "-config-file=" + configTarget}),
 		helpers.Image(name, config.Image+":"+config.Tag),
 		helpers.Labels(name, map[string]string{compose.ADAppTypeLabelKey: ConsulName}),
+		helpers.Environment(name, helpers.Env{Name: "CONSUL_HTTP_ADDR", Value: utils.Ptr(addr)}),
+		helpers.Secrets(name, helpers.Secret{Source: ConfigJson, Target: configTarget, FileMode: 0o400}),
+		helpers.Extension(name, XSecretsKey, &XSecrets{Data: xsecretsData}),
+		helpers.Volumes(name, volume+":"+ConsulDataMountPath),
+		helpers.HealthCheck(name, helpers.HealthCheckConfig{
+			Cmd:         []string{"CMD-SHELL", healthCheckCommand},
+			Interval:    5 * time.Second,
+			Timeout:     3 * time.Second,
+			StartPeriod: time.Minute,
+			Retries:     5,
+		}),
 	)
 
 	if config.PublishPort > 0 {
-		prj.AppendHelpers(helpers.PublishPort(name, config.PublishPort, ConsulPublishPort))
+		prj.AppendHelpers(helpers.PublishPort(name, config.PublishPort+uint16(i), port))
+	}
+}
+
+// consulTLS mounts the certificates to the server and enables TLS of the API
+// and of the RPC between the servers. The HTTP port is disabled.
+func (prj *Project) consulTLS(cluster *consulCluster, name string, consulConfig map[string]any, data map[string]string) {
+	config := cluster.config
+
+	if prj.ca != nil {
+		data[PemCa] = prj.ca.CertPEM()
+		// the servers verify each other by the server.<datacenter>.consul name
+		data[PemCert], data[PemKey] = prj.peerCert(name, fmt.Sprintf("server.%s.consul", config.Datacenter))
+	}
+	for k, file := range map[string]string{PemCa: config.SSLCaFile, PemCert: config.SSLCertFile, PemKey: config.SSLKeyFile} {
+		if len(file) > 0 {
+			b, err := os.ReadFile(file)
+			checkErr(err)
+			data[k] = string(b)
+		}
 	}
+
+	caTarget := path.Join(helpers.SecretsPath, PemCa)
+	certTarget := path.Join(helpers.SecretsPath, PemCert)
+	keyTarget := path.Join(helpers.SecretsPath, PemKey)
+
+	consulConfig["ports"] = map[string]int{"http": -1, "https": int(ConsulPublishSSLPort)}
+	consulConfig["tls"] = map[string]any{
+		"defaults": map[string]any{
+			"ca_file":         caTarget,
+			"cert_file":       certTarget,
+			"key_file":        keyTarget,
+			"verify_outgoing": true,
+		},
+		"internal_rpc": map[string]any{
+			"verify_incoming":        true,
+			"verify_server_hostname": true,
+		},
+	}
+
+	prj.AppendHelpers(
+		helpers.Secrets(name,
+			helpers.Secret{Source: PemCa, Target: caTarget, FileMode: 0o440},
+			helpers.Secret{Source: PemCert, Target: certTarget, FileMode: 0o440},
+			helpers.Secret{Source: PemKey, Target: keyTarget, FileMode: 0o400},
+		),
+		helpers.Environment(name, helpers.Env{Name: "CONSUL_CACERT", Value: utils.Ptr(caTarget)}),
+	)
 }
@@ -27,11 +27,22 @@ const (
 	ADPGPublishPort   uint16 = 5432
 	ADPGDataMountPath        = "/data"
 
-	ConsulImage              = "hub.arenadata.io/adcm/consul"
-	ConsulTag                = "v0.0.0"
-	ConsulPublishPort uint16 = 8500
+	ConsulImage                 = "hub.arenadata.io/adcm/consul"
+	ConsulTag                   = "v0.0.0"
+	ConsulPublishPort    uint16 = 8500
+	ConsulPublishSSLPort uint16 = 8501
+	ConsulDataMountPath         = "/consul/data"
 
-	VaultImage              = "openbao/openbao"
-	VaultTag                = "2.2.0"
-	VaultPublishPort uint16 = 8200
+	VaultImage                = "openbao/openbao"
+	VaultTag                  = "2.2.0"
+	VaultPublishPort   uint16 = 8200
+	VaultClusterPort   uint16 = 8201
+	VaultDataMountPath        = "/openbao/data"
+
+	ProxyImage = "haproxy"
+	ProxyTag   = "3.0-alpine"
+
+	NginxImage        = "nginxinc/nginx-unprivileged"
+	NginxTag          = "1.27-alpine"
+	NginxPort  uint16 = 8080
 )
@@ -0,0 +1,394 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/arenadata/adcm-installer/assets"
+	"github.com/arenadata/adcm-installer/pkg/compose"
+
+	composeTypes "github.com/compose-spec/compose-go/v2/types"
+	"github.com/containerd/errdefs"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultJobRetries = 1
+
+	// jobLogTail is the number of the log lines of a failed job in its error
+	jobLogTail = 20
+)
+
+// Job is a one-off container which has to complete before the services are
+// started, e.g. changing the owner of a volume or initializing a database.
+type Job struct {
+	Service composeTypes.ServiceConfig
+	// DependsOn are the jobs which have to complete before this one
+	DependsOn []string
+	// Marker is the file in the first volume of the job which records its
+	// completion. The job is skipped while the marker matches its
+	// configuration. A job without a marker is always run.
+	Marker string
+}
+
+// JobError is the failure of a job, Logs are the last lines of its output.
+type JobError struct {
+	Job       string
+	Container string
+	ExitCode  int
+	Attempts  int
+	Logs      []string
+}
+
+func (e *JobError) Error() string {
+	msg := fmt.Sprintf("init job %s failed with exit code %d after %d attempt(s)", e.Job, e.ExitCode, e.Attempts)
+	if len(e.Logs) > 0 {
+		msg += ", last lines of the " + e.Container + " container log:\n  " + strings.Join(e.Logs, "\n  ")
+	}
+	return msg
+}
+
+// ExtractJobs moves the services labeled as init jobs out of the services of
+// the project. They are kept as disabled services, so docker compose does not
+// treat their containers as orphans. The compose dependencies between jobs
+// become the job dependencies.
+func ExtractJobs(prj *composeTypes.Project) map[string]*Job {
+	jobs := map[string]*Job{}
+	for name, svc := range prj.Services {
+		marker, ok := svc.Labels[compose.ADJobLabelKey]
+		if !ok {
+			continue
+		}
+
+		job := &Job{Marker: marker}
+		for dep := range svc.DependsOn {
+			job.DependsOn = append(job.DependsOn, dep)
+		}
+		sort.Strings(job.DependsOn)
+
+		svc.DependsOn = nil
+		svc.Profiles = nil
+		job.Service = svc
+		jobs[name] = job
+
+		delete(prj.Services, name)
+		if prj.DisabledServices == nil {
+			prj.DisabledServices = composeTypes.Services{}
+		}
+		prj.DisabledServices[name] = svc
+	}
+	return jobs
+}
+
+// JobRunner runs the init jobs of a project in their dependency order.
+type JobRunner struct {
+	comp *compose.Compose
+	prj  *composeTypes.Project
+	jobs map[string]*Job
+
+	// MacKey is the key of the secrets fingerprints in the markers
+	MacKey []byte
+	// Retries is the number of re-runs of a failed job
+	Retries int
+	// Timeout is how long to wait for a job to complete
+	Timeout time.Duration
+	// Keep keeps the job containers for debugging
+	Keep bool
+}
+
+func NewJobRunner(comp *compose.Compose, prj *composeTypes.Project, jobs map[string]*Job) *JobRunner {
+	return &JobRunner{comp: comp, prj: prj, jobs: jobs, Retries: DefaultJobRetries}
+}
+
+// Order returns the job names sorted so that every job follows the jobs it
+// depends on.
+func (r *JobRunner) Order() ([]string, error) {
+	names := make([]string, 0, len(r.jobs))
+	for name := range r.jobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := map[string]int{}
+	var order []string
+
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("init jobs depend on each other: %s", strings.Join(append(path, name), " -> "))
+		}
+
+		job, ok := r.jobs[name]
+		if !ok {
+			return fmt.Errorf("init job %s depends on unknown job %s", path[len(path)-1], name)
+		}
+
+		state[name] = visiting
+		for _, dep := range job.DependsOn {
+			if err := visit(dep, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		order = append(order, name)
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// Run runs the jobs one by one. A failed job is re-run up to Retries times,
+// the jobs depending on it are not run. The job containers, failed or not, are
+// removed unless Keep is set.
+func (r *JobRunner) Run(ctx context.Context) error {
+	if len(r.jobs) == 0 {
+		return nil
+	}
+
+	order, err := r.Order()
+	if err != nil {
+		return err
+	}
+
+	if err = assets.LoadBusyboxImage(ctx); err != nil {
+		return err
+	}
+
+	for _, name := range order {
+		if err = r.run(ctx, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *JobRunner) run(ctx context.Context, name string) error {
+	job := r.jobs[name]
+	logger := log.WithField("job", name)
+
+	var fingerprint string
+	if len(job.Marker) > 0 {
+		var err error
+		if fingerprint, err = r.fingerprint(job); err != nil {
+			return err
+		}
+
+		done, err := r.markerMatches(ctx, job, fingerprint)
+		if err != nil {
+			return fmt.Errorf("init job %s: %v", name, err)
+		}
+		if done {
+			logger.Infof("Init job %s has already been done, skipped", name)
+			return nil
+		}
+	}
+
+	jobPrj := r.project(name)
+	attempts := r.Retries + 1
+	for attempt := 1; attempt <= attempts; attempt++ {
+		logger.Infof("Running init job %s", name)
+
+		state, err := r.runOnce(ctx, jobPrj, name)
+		if err != nil {
+			return fmt.Errorf("init job %s: %v", name, err)
+		}
+
+		logs := new(bytes.Buffer)
+		if err = r.comp.ContainerLogs(ctx, state.Container, logs); err != nil {
+			logger.Warnf("Reading the logs of %s failed: %v", state.Container, err)
+		}
+		for _, line := range logLines(logs.Bytes()) {
+			logger.Debug(line)
+		}
+
+		if state.ExitCode == 0 {
+			logger.Infof("Init job %s completed", name)
+			break
+		}
+
+		if attempt == attempts {
+			if !r.Keep {
+				if err = r.comp.Remove(ctx, jobPrj, name); err != nil {
+					logger.Warnf("Removing init job container failed: %v", err)
+				}
+			}
+			return &JobError{
+				Job:       name,
+				Container: state.Container,
+				ExitCode:  state.ExitCode,
+				Attempts:  attempts,
+				Logs:      tail(logLines(logs.Bytes()), jobLogTail),
+			}
+		}
+
+		logger.Warnf("Init job %s failed with exit code %d, retrying (%d/%d)", name, state.ExitCode, attempt, r.Retries)
+		if err = r.comp.Remove(ctx, jobPrj, name); err != nil {
+			return fmt.Errorf("init job %s: %v", name, err)
+		}
+	}
+
+	if len(fingerprint) > 0 {
+		if err := r.writeMarker(ctx, job, fingerprint); err != nil {
+			return fmt.Errorf("init job %s: marker: %v", name, err)
+		}
+	}
+
+	if !r.Keep {
+		if err := r.comp.Remove(ctx, jobPrj, name); err != nil {
+			logger.Warnf("Removing init job container failed: %v", err)
+		}
+	}
+	return nil
+}
+
+func (r *JobRunner) runOnce(ctx context.Context, jobPrj *composeTypes.Project, name string) (compose.ServiceState, error) {
+	if err := r.comp.Up(ctx, jobPrj, false); err != nil {
+		return compose.ServiceState{}, err
+	}
+
+	states, err := r.comp.WaitForExit(ctx, jobPrj, []string{name}, r.Timeout)
+	if err != nil {
+		return compose.ServiceState{}, err
+	}
+	return states[name], nil
+}
+
+// project returns the project with the job as its only service.
+func (r *JobRunner) project(name string) *composeTypes.Project {
+	p := *r.prj
+	p.Services = composeTypes.Services{name: r.jobs[name].Service}
+	p.DisabledServices = composeTypes.Services{}
+	for n, svc := range r.prj.Services {
+		p.DisabledServices[n] = svc
+	}
+	for n, job := range r.jobs {
+		if n != name {
+			p.DisabledServices[n] = job.Service
+		}
+	}
+	return &p
+}
+
+// fingerprint identifies the configuration of the job including the values
+// of its secrets.
+func (r *JobRunner) fingerprint(job *Job) (string, error) {
+	hash, err := compose.ServiceHash(job.Service)
+	if err != nil {
+		return "", err
+	}
+	fields, err := compose.FieldHashes(r.prj, job.Service, r.MacKey)
+	if err != nil {
+		return "", err
+	}
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	_, _ = fmt.Fprintf(h, "%s\x00", hash)
+	for _, k := range keys {
+		_, _ = fmt.Fprintf(h, "%s\x00%s\x00", k, fields[k])
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// markerVolume returns the first volume of the job, the marker is kept there.
+func (r *JobRunner) markerVolume(job *Job) (compose.Volume, error) {
+	if len(job.Service.Volumes) == 0 {
+		return compose.Volume{}, fmt.Errorf("job with marker %s has no volume", job.Marker)
+	}
+
+	mnt := job.Service.Volumes[0]
+	v := compose.Volume{Type: mnt.Type, Source: mnt.Source}
+	if mnt.Type == composeTypes.VolumeTypeVolume {
+		if vol, ok := r.prj.Volumes[mnt.Source]; ok && len(vol.Name) > 0 {
+			v.Source = vol.Name
+		}
+	}
+	return v, nil
+}
+
+func (r *JobRunner) markerMatches(ctx context.Context, job *Job, fingerprint string) (bool, error) {
+	v, err := r.markerVolume(job)
+	if err != nil {
+		return false, err
+	}
+
+	if v.Type == composeTypes.VolumeTypeVolume {
+		exists, err := r.comp.VolumeExists(ctx, v.Source)
+		if err != nil || !exists {
+			return false, err
+		}
+	}
+
+	b, err := r.comp.VolumeReadFile(ctx, assets.ImageName, v, job.Marker)
+	if errdefs.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(string(b)) == fingerprint, nil
+}
+
+func (r *JobRunner) writeMarker(ctx context.Context, job *Job, fingerprint string) error {
+	v, err := r.markerVolume(job)
+	if err != nil {
+		return err
+	}
+	return r.comp.VolumeWriteFile(ctx, assets.ImageName, v, job.Marker, []byte(fingerprint+"\n"))
+}
+
+func logLines(b []byte) []string {
+	var lines []string
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for sc.Scan() {
+		lines = append(lines, sc.Text())
+	}
+	return lines
+}
+
+func tail(lines []string, n int) []string {
+	if len(lines) > n {
+		return lines[len(lines)-n:]
+	}
+	return lines
+}
@@ -0,0 +1,131 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/arenadata/adcm-installer/pkg/compose"
+
+	composeTypes "github.com/compose-spec/compose-go/v2/types"
+)
+
+func testJobs(deps map[string][]string) map[string]*Job {
+	jobs := make(map[string]*Job, len(deps))
+	for name, d := range deps {
+		jobs[name] = &Job{Service: composeTypes.ServiceConfig{Name: name}, DependsOn: d}
+	}
+	return jobs
+}
+
+func TestJobRunner_Order(t *testing.T) {
+	tests := []struct {
+		name      string
+		deps      map[string][]string
+		want      []string
+		wantError string
+	}{
+		{"Empty", map[string][]string{}, nil, ""},
+		{"Independent", map[string][]string{"chown-consul": nil, "chown-adpg": nil}, []string{"chown-adpg", "chown-consul"}, ""},
+		{"Dependency", map[string][]string{"init-adpg": {"chown-adpg"}, "chown-adpg": nil}, []string{"chown-adpg", "init-adpg"}, ""},
+		{"Chain", map[string][]string{"a": {"b"}, "b": {"c"}, "c": nil}, []string{"c", "b", "a"}, ""},
+		{"Diamond", map[string][]string{"a": {"b", "c"}, "b": {"d"}, "c": {"d"}, "d": nil}, []string{"d", "b", "c", "a"}, ""},
+		{"Cycle", map[string][]string{"a": {"b"}, "b": {"a"}}, nil, "a -> b -> a"},
+		{"SelfCycle", map[string][]string{"a": {"a"}}, nil, "a -> a"},
+		{"UnknownDependency", map[string][]string{"init-adpg": {"chown-adpg"}}, nil, "init-adpg depends on unknown job chown-adpg"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewJobRunner(nil, &composeTypes.Project{}, testJobs(tt.deps)).Order()
+			if len(tt.wantError) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+					t.Fatalf("error = %v, want %q", err, tt.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Order() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractJobs(t *testing.T) {
+	prj := &composeTypes.Project{Services: composeTypes.Services{
+		"adpg": {Name: "adpg"},
+		"chown-adpg": {
+			Name:   "chown-adpg",
+			Labels: composeTypes.Labels{compose.ADJobLabelKey: ""},
+		},
+		"init-adpg": {
+			Name:   "init-adpg",
+			Labels: composeTypes.Labels{compose.ADJobLabelKey: ".adi-init-adpg"},
+			DependsOn: composeTypes.DependsOnConfig{
+				"chown-adpg": {Condition: composeTypes.ServiceConditionCompletedSuccessfully},
+			},
+		},
+	}}
+
+	jobs := ExtractJobs(prj)
+
+	if got := prj.ServiceNames(); !reflect.DeepEqual(got, []string{"adpg"}) {
+		t.Errorf("services = %v, want [adpg]", got)
+	}
+	if _, ok := prj.DisabledServices["init-adpg"]; !ok {
+		t.Error("init-adpg is not a disabled service")
+	}
+	if len(jobs) != 2 {
+		t.Fatalf("jobs = %v, want 2", jobs)
+	}
+
+	job := jobs["init-adpg"]
+	if job.Marker != ".adi-init-adpg" {
+		t.Errorf("marker = %q", job.Marker)
+	}
+	if !reflect.DeepEqual(job.DependsOn, []string{"chown-adpg"}) {
+		t.Errorf("depends on = %v", job.DependsOn)
+	}
+	if len(job.Service.DependsOn) > 0 {
+		t.Errorf("service depends on = %v, want none", job.Service.DependsOn)
+	}
+}
+
+func TestLogLines(t *testing.T) {
+	tests := []struct {
+		name string
+		logs string
+		n    int
+		want []string
+	}{
+		{"Empty", "", 2, nil},
+		{"NoTrailingNewline", "a\nb", 5, []string{"a", "b"}},
+		{"Tail", "a\nb\nc\n", 2, []string{"b", "c"}},
+		{"CRLF", "a\r\nb\r\n", 2, []string{"a", "b"}},
+		{"Exact", "a\nb\n", 2, []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tail(logLines([]byte(tt.logs)), tt.n); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
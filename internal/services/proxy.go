@@ -0,0 +1,199 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package services
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/arenadata/adcm-installer/internal/services/helpers"
+	"github.com/arenadata/adcm-installer/pkg/compose"
+
+	composeTypes "github.com/compose-spec/compose-go/v2/types"
+)
+
+const proxyStickyCookie = "ADI_ADCM"
+
+type ProxyConfig struct {
+	enable bool
+	url    string
+
+	Image string `yaml:"proxy-image"`
+	Tag   string `yaml:"proxy-tag"`
+}
+
+// proxy adds the HAProxy service in front of the ADCM instances. The ports,
+// certificates and url of ADCM apply to the proxy, the instances are not
+// published.
+func (prj *Project) proxy(adcmNames []string) {
+	config := prj.config.Proxy
+	if !config.enable {
+		return
+	}
+
+	name := ProxyName
+	addService(name, prj.prj)
+
+	adcmConfig := prj.config.Adcm
+	defaultUrl := fmt.Sprintf("http://%s:%d", adcmConfig.ip, adcmConfig.PublishPort)
+	if len(adcmConfig.Url) == 0 {
+		adcmConfig.Url = defaultUrl
+	}
+
+	if prj.interactive {
+		checkErr(readValue(&config.Image, &prompt{msg: "Proxy image", def: config.Image}))
+		checkErr(readValue(&config.Tag, &prompt{msg: "Proxy image tag", def: config.Tag}))
+
+		portStr := strconv.Itoa(int(adcmConfig.PublishPort))
+		checkErr(readValue(&adcmConfig.PublishPort, &prompt{msg: "Proxy: ADCM publish port", def: portStr}))
+		if adcmConfig.Url == defaultUrl {
+			adcmConfig.Url = fmt.Sprintf("http://%s:%d", adcmConfig.ip, adcmConfig.PublishPort)
+		}
+
+		prj.readHTTPSConfig(name, &adcmConfig)
+	}
+
+	tlsEnabled := prj.checkHTTPSConfig(name, &adcmConfig)
+	if prj.interactive {
+		checkErr(readValue(&adcmConfig.Url, &prompt{msg: "Proxy: ADCM url", def: adcmConfig.Url}))
+	}
+	prj.config.Proxy.url = adcmConfig.Url
+
+	xsecretsData := map[string]string{}
+	if tlsEnabled {
+		if prj.ca != nil {
+			xsecretsData[PemCert], xsecretsData[PemKey] = prj.serverCert(name, hostIPs(adcmConfig.ip)...)
+		}
+		readHTTPSFiles(&adcmConfig, xsecretsData)
+
+		// HAProxy loads the key of the certificate from the file with the .key suffix
+		prj.AppendHelpers(
+			helpers.Secrets(name,
+				helpers.Secret{
+					Source:   PemKey,
+					Target:   path.Join(helpers.SecretsPath, PemCert+".key"),
+					FileMode: 0o400,
+				},
+				helpers.Secret{
+					Source:   PemCert,
+					Target:   path.Join(helpers.SecretsPath, PemCert),
+					FileMode: 0o440,
+				},
+			),
+			helpers.PublishPort(name, adcmConfig.PublishSSLPort, ADCMPublishSSLPort),
+		)
+	}
+
+	if prj.crypt != nil {
+		var err error
+		for k, v := range xsecretsData {
+			v, err = prj.crypt.EncryptValue(v, name, k)
+			checkErr(err)
+			xsecretsData[k] = v
+		}
+	}
+
+	var depends []helpers.Depended
+	for _, adcmName := range adcmNames {
+		depends = append(depends, helpers.Depended{Service: adcmName, Condition: composeTypes.ServiceConditionStarted})
+	}
+
+	httpServed := !adcmConfig.DisableHTTP && adcmConfig.PublishPort > 0
+	conf := prj.haproxyConf(adcmNames, tlsEnabled, httpServed, adcmConfig.HTTPSRedirect, adcmConfig.PublishSSLPort)
+	script := fmt.Sprintf("echo '%s' > /tmp/haproxy.cfg && exec haproxy -W -db -f /tmp/haproxy.cfg", conf)
+
+	prj.AppendHelpers(
+		helpers.Hostname(name, prj.hostname(name)),
+		helpers.Image(name, config.Image+":"+config.Tag),
+		helpers.Entrypoint(name, "/bin/sh", "-c", script),
+		helpers.Labels(name, map[string]string{compose.ADAppTypeLabelKey: ProxyName}),
+		helpers.DependsOn(name, depends...),
+	)
+
+	if len(xsecretsData) > 0 {
+		prj.AppendHelpers(helpers.Extension(name, XSecretsKey, &XSecrets{Data: xsecretsData}))
+	}
+
+	if httpServed {
+		prj.AppendHelpers(helpers.PublishPort(name, adcmConfig.PublishPort, ADCMPublishPort))
+	}
+}
+
+// haproxyConf returns the HAProxy configuration balancing across the ADCM
+// instances. Clients stick to an instance by a cookie, the instances failing
+// the health check are taken out of the balancing. The servers are resolved by
+// the docker DNS, so the proxy follows recreated containers.
+func (prj *Project) haproxyConf(adcmNames []string, tls, http, redirect bool, publishSSLPort uint16) string {
+	b := new(strings.Builder)
+	lines := func(l ...string) {
+		for _, s := range l {
+			b.WriteString(s + "\n")
+		}
+	}
+
+	lines(
+		"global",
+		"  pidfile /tmp/haproxy.pid",
+		"  log stdout format raw local0 notice",
+		"",
+		"resolvers docker",
+		"  nameserver dns 127.0.0.11:53",
+		"  hold valid 10s",
+		"",
+		"defaults",
+		"  mode http",
+		"  log global",
+		"  option forwardfor",
+		"  timeout connect 5s",
+		"  timeout client 5m",
+		"  timeout server 5m",
+		"  default-server init-addr last,libc,none resolvers docker check inter 5s fall 3 rise 2",
+		"",
+		"frontend adcm",
+	)
+
+	if http {
+		lines(fmt.Sprintf("  bind :%d", ADCMPublishPort))
+	}
+	if tls {
+		lines(
+			fmt.Sprintf("  bind :%d ssl crt %s", ADCMPublishSSLPort, path.Join(helpers.SecretsPath, PemCert)),
+			"  http-request set-header X-Forwarded-Proto https if { ssl_fc }",
+		)
+		if redirect {
+			lines(fmt.Sprintf(
+				"  http-request redirect location https://%%[req.hdr(host),field(1,:)]:%d%%[capture.req.uri] code 301 unless { ssl_fc }",
+				publishSSLPort))
+		}
+	}
+
+	lines(
+		"  default_backend adcm",
+		"",
+		"backend adcm",
+		"  balance roundrobin",
+		fmt.Sprintf("  cookie %s insert indirect nocache httponly", proxyStickyCookie),
+		"  option httpchk GET "+adcmHealthPath,
+		"  http-check expect rstatus ^"+adcmHealthyStatus,
+	)
+	for _, adcmName := range adcmNames {
+		lines(fmt.Sprintf("  server %s %s:%d cookie %s", adcmName, prj.hostname(adcmName), ADCMPublishPort, adcmName))
+	}
+
+	return b.String()
+}
@@ -0,0 +1,74 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package services
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/arenadata/adcm-installer/internal/services/helpers"
+
+	composeTypes "github.com/compose-spec/compose-go/v2/types"
+)
+
+func TestHaproxyConf(t *testing.T) {
+	prj := &Project{prj: &composeTypes.Project{Name: "demo"}}
+
+	httpBind := fmt.Sprintf("  bind :%d\n", ADCMPublishPort)
+	tlsBind := fmt.Sprintf("  bind :%d ssl crt %s/%s\n", ADCMPublishSSLPort, helpers.SecretsPath, PemCert)
+	// the published port differs from the one HAProxy listens on
+	redirect := "  http-request redirect location https://%[req.hdr(host),field(1,:)]:9443%[capture.req.uri] code 301 unless { ssl_fc }\n"
+
+	tests := []struct {
+		name                string
+		tls, http, redirect bool
+		want, notWant       []string
+	}{
+		{"HTTP", false, true, false, []string{httpBind}, []string{tlsBind, redirect}},
+		{"TLS", true, false, false, []string{tlsBind}, []string{httpBind, redirect}},
+		{"TLSAndHTTP", true, true, false, []string{httpBind, tlsBind}, []string{redirect}},
+		{"Redirect", true, true, true, []string{httpBind, tlsBind, redirect}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := prj.haproxyConf([]string{"adcm-1", "adcm-2"}, tt.tls, tt.http, tt.redirect, 9443)
+
+			// the configuration is written by a single quoted shell echo
+			if strings.Contains(conf, "'") {
+				t.Errorf("configuration contains a single quote:\n%s", conf)
+			}
+
+			want := append([]string{
+				"  cookie ADI_ADCM insert indirect nocache httponly\n",
+				"  option httpchk GET " + adcmHealthPath + "\n",
+				"  http-check expect rstatus ^" + adcmHealthyStatus + "\n",
+				fmt.Sprintf("  server adcm-1 demo-adcm-1:%d cookie adcm-1\n", ADCMPublishPort),
+				fmt.Sprintf("  server adcm-2 demo-adcm-2:%d cookie adcm-2\n", ADCMPublishPort),
+			}, tt.want...)
+			for _, s := range want {
+				if !strings.Contains(conf, s) {
+					t.Errorf("configuration does not contain %q:\n%s", s, conf)
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(conf, s) {
+					t.Errorf("configuration contains %q:\n%s", s, conf)
+				}
+			}
+		})
+	}
+}
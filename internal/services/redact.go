@@ -0,0 +1,93 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package services
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// publicSecrets are the x-secrets values which are not secret: the database
+// identifiers and the certificates. They are kept readable in the logs.
+var publicSecrets = map[string]bool{
+	"db-host":    true,
+	"db-port":    true,
+	PgDbName:     true,
+	PgDbUser:     true,
+	PemCert:      true,
+	PemCa:        true,
+	PgSslCaKey:   true,
+	PgSslCertKey: true,
+	VaultSealCa:  true,
+}
+
+// SecretValues returns the values of the decrypted x-secrets of a service
+// which are secret: passwords, private keys, tokens and unseal keys. Only the
+// secret fields of the JSON values, e.g. the configuration files and the
+// unseal data, are returned.
+func SecretValues(data map[string]string) []string {
+	var out []string
+	for k, v := range data {
+		if publicSecrets[k] || len(v) == 0 {
+			continue
+		}
+
+		var doc map[string]any
+		if err := json.Unmarshal([]byte(v), &doc); err == nil {
+			out = appendSecretFields(out, doc, false)
+			continue
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+// appendSecretFields appends the strings of the secret fields of the JSON
+// value, all the strings are appended below a secret field.
+func appendSecretFields(out []string, v any, secret bool) []string {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, field := range v {
+			out = appendSecretFields(out, field, secret || isSecretField(k))
+		}
+	case []any:
+		for _, item := range v {
+			out = appendSecretFields(out, item, secret)
+		}
+	case string:
+		if secret && len(v) > 0 {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// isSecretField reports whether the JSON field holds a secret, e.g. encrypt,
+// root_token or unseal_keys_b64, but not tls_key_file or key_name.
+func isSecretField(name string) bool {
+	var secret bool
+	for _, word := range strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return r == '_' || r == '-' || r == '.'
+	}) {
+		switch word {
+		case "name", "file", "path", "id", "cert", "ca":
+			return false
+		case "encrypt", "key", "keys", "password", "secret", "token", "tokens":
+			secret = true
+		}
+	}
+	return secret
+}
@@ -0,0 +1,70 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package services
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestSecretValues(t *testing.T) {
+	tests := []struct {
+		name string
+		data map[string]string
+		want []string
+	}{
+		{"Database", map[string]string{PgDbName: "adcm", PgDbUser: "adcm", PgDbPass: "s3cr3t", "db-host": "adpg"}, []string{"s3cr3t"}},
+		{"Password", map[string]string{"password": "s3cr3t"}, []string{"s3cr3t"}},
+		{"Certificates", map[string]string{PemCa: "ca", PemCert: "cert", PemKey: "private"}, []string{"private"}},
+		{"UnsealData", map[string]string{VaultUnsealData: `{"unseal_keys_b64":["k1","k2"],"unseal_threshold":2,"root_token":"t"}`},
+			[]string{"k1", "k2", "t"}},
+		{"ConsulConfig", map[string]string{ConfigJson: `{"datacenter":"dc1","encrypt":"gossip","acl":{"tokens":{"agent":"a"}},"tls":{"defaults":{"key_file":"/run/secrets/key.pem"}}}`},
+			[]string{"a", "gossip"}},
+		{"TransitSeal", map[string]string{ConfigJson: `{"seal":{"transit":{"token":"t","key_name":"autounseal","mount_path":"transit/"}}}`},
+			[]string{"t"}},
+		{"Empty", map[string]string{PgDbPass: ""}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SecretValues(tt.data)
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSecretValuesKeepServiceNames(t *testing.T) {
+	// every returned value is redacted in the logs, so the service, database
+	// and user names must not be among them
+	data := []map[string]string{
+		{PgDbName: AdcmName, PgDbUser: AdcmName, PgDbPass: "adcm-s3cr3t", "db-host": AdpgName, "db-port": "5432"},
+		{PgDbName: VaultName, PgDbUser: VaultName, PgDbPass: "vault-s3cr3t"},
+	}
+
+	var got []string
+	for _, d := range data {
+		got = append(got, SecretValues(d)...)
+	}
+	sort.Strings(got)
+
+	want := []string{"adcm-s3cr3t", "vault-s3cr3t"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -18,14 +18,19 @@ package services
 import (
 	"fmt"
 	"io"
+	"net"
 	"os"
+	"path"
+	"slices"
 	"sort"
 	"strconv"
 
 	"github.com/arenadata/adcm-installer/internal/services/helpers"
+	"github.com/arenadata/adcm-installer/pkg/certs"
 	"github.com/arenadata/adcm-installer/pkg/compose"
 	"github.com/arenadata/adcm-installer/pkg/secrets"
 	"github.com/arenadata/adcm-installer/pkg/utils"
+	"github.com/arenadata/adcm-installer/pkg/vault/unseal/runner"
 
 	"github.com/AlecAivazis/survey/v2"
 	composeTypes "github.com/compose-spec/compose-go/v2/types"
@@ -53,21 +58,38 @@ const (
 	VaultDeployModeHa    = "ha"
 	VaultDeployModeDev   = "dev"
 	VaultUnsealData      = "unseal-data"
+	VaultUnsealShare     = "unseal-share"
+
+	VaultStoragePostgresql = "postgresql"
+	VaultStorageRaft       = "raft"
+	VaultStorageFile       = "file"
+
+	VaultSealTypeShamir  = "shamir"
+	VaultSealTypeStatic  = "static"
+	VaultSealTypeTransit = "transit"
+	VaultSealKey         = "seal.key"
+	VaultSealCa          = "seal-ca.pem"
 
 	AdcmName   = "adcm"
 	AdpgName   = "adpg"
 	ConsulName = "consul"
 	VaultName  = "vault"
-	PauseName  = "pause"
+
+	ProxyName    = "proxy"
+	RedirectName = "redirect"
 
 	ConfigJson = "config.json"
 	PemKey     = "key.pem"
 	PemCert    = "cert.pem"
 	PemCa      = "ca.pem"
+	PemCaKey   = "ca-key.pem"
+
+	TLSModeNone = "none"
+	TLSModeAuto = "auto"
 
-	XSecretsKey = "x-secrets"
+	XSecretsKey  = "x-secrets"
+	VaultInitKey = "x-vault-init"
 
-	InitContainerProfile    = "init"
 	PrimaryContainerProfile = "primary"
 )
 
@@ -86,19 +108,63 @@ var (
 		VaultDeployModeHa,
 		VaultDeployModeDev,
 	}
+
+	allowVaultStorages = []string{
+		VaultStoragePostgresql,
+		VaultStorageRaft,
+		VaultStorageFile,
+	}
+
+	allowVaultSeals = []string{
+		VaultSealTypeShamir,
+		VaultSealTypeStatic,
+		VaultSealTypeTransit,
+	}
 )
 
 type XSecrets struct {
-	AgeRecipient string            `yaml:"age_recipient,omitempty" mapstructure:"age_recipient,omitempty"`
-	Key          string            `yaml:"key,omitempty" mapstructure:"key,omitempty"`
-	Data         map[string]string `yaml:"data,omitempty" mapstructure:"data,omitempty"`
-	UnMapped     map[string]string `yaml:"un-mapped,omitempty" mapstructure:"un-mapped,omitempty"`
+	AgeRecipient  string            `yaml:"age_recipient,omitempty" mapstructure:"age_recipient,omitempty"`
+	AgeRecipients []string          `yaml:"age_recipients,omitempty" mapstructure:"age_recipients,omitempty"`
+	Key           string            `yaml:"key,omitempty" mapstructure:"key,omitempty"`
+	Data          map[string]string `yaml:"data,omitempty" mapstructure:"data,omitempty"`
+	UnMapped      map[string]string `yaml:"un-mapped,omitempty" mapstructure:"un-mapped,omitempty"`
+}
+
+// Recipients returns all age recipients the master key is encrypted for.
+func (x *XSecrets) Recipients() []string {
+	var out []string
+	if len(x.AgeRecipient) > 0 {
+		out = append(out, x.AgeRecipient)
+	}
+	for _, r := range x.AgeRecipients {
+		if !slices.Contains(out, r) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// SetRecipients stores the recipients list. A single recipient is kept in
+// age_recipient so that such files stay readable by older versions.
+func (x *XSecrets) SetRecipients(recipients []string) {
+	x.AgeRecipient = ""
+	x.AgeRecipients = nil
+	if len(recipients) == 1 {
+		x.AgeRecipient = recipients[0]
+		return
+	}
+	x.AgeRecipients = recipients
+}
+
+func (x *XSecrets) HasRecipient(recipient string) bool {
+	return slices.Contains(x.Recipients(), recipient)
 }
 
 type InitConfig struct {
 	Adcm   AdcmConfig   `yaml:",inline"`
 	Adpg   AdpgConfig   `yaml:",inline"`
 	Consul ConsulConfig `yaml:",inline"`
+	Proxy  ProxyConfig  `yaml:",inline"`
 	Vault  VaultConfig  `yaml:",inline"`
 }
 
@@ -108,6 +174,7 @@ type Project struct {
 	config             *InitConfig
 	interactive        bool
 	crypt              secrets.Secrets
+	ca                 *certs.CA
 }
 
 func New(name string, opts ...ProjectOption) (*Project, error) {
@@ -137,12 +204,17 @@ func (prj *Project) Build() error {
 		checkErr(readValue(&prj.config.Adcm.Count, &prompt{msg: "Number of ADCM instances", def: adcmCount}))
 	}
 
+	adcmNames := []string{AdcmName}
 	if prj.config.Adcm.Count > 1 {
+		adcmNames = adcmNames[:0]
 		for i := uint8(1); i <= prj.config.Adcm.Count; i++ {
-			prj.adcm(fmt.Sprintf("adcm-%d", i))
+			adcmNames = append(adcmNames, fmt.Sprintf("adcm-%d", i))
 		}
-	} else {
-		prj.adcm("")
+	}
+
+	prj.proxy(adcmNames)
+	for _, name := range adcmNames {
+		prj.adcm(name)
 	}
 
 	prj.consul()
@@ -180,17 +252,91 @@ func (prj *Project) hostname(name string) string {
 	return prj.prj.Name + "-" + name
 }
 
+// serverCert issues the certificate of the service for its hostname, the
+// service name and the loopback address.
+func (prj *Project) serverCert(name string, ips ...net.IP) (string, string) {
+	hostname := prj.hostname(name)
+	cert, key, err := prj.ca.Issue(certs.Request{
+		CommonName: hostname,
+		DNSNames:   []string{hostname, name, "localhost"},
+		IPs:        append([]net.IP{net.IPv4(127, 0, 0, 1)}, ips...),
+	})
+	checkErr(err)
+	return cert, key
+}
+
+// peerCert issues the certificate of a cluster member, used as both the server
+// and the client one.
+func (prj *Project) peerCert(name string, dnsNames ...string) (string, string) {
+	hostname := prj.hostname(name)
+	cert, key, err := prj.ca.Issue(certs.Request{
+		CommonName: hostname,
+		DNSNames:   append([]string{hostname, name, "localhost"}, dnsNames...),
+		IPs:        []net.IP{net.IPv4(127, 0, 0, 1)},
+		Usage:      certs.UsagePeer,
+	})
+	checkErr(err)
+	return cert, key
+}
+
+// clientCert issues the certificate for the database user.
+func (prj *Project) clientCert(user string) (string, string) {
+	cert, key, err := prj.ca.Issue(certs.Request{CommonName: user, Usage: certs.UsageClient})
+	checkErr(err)
+	return cert, key
+}
+
+// dbSSLSecrets mounts the database SSL files to the service. The files are
+// read into data unless they have already been issued. Returns the targets of
+// the mounted files.
+func (prj *Project) dbSSLSecrets(name, caFile, certFile, keyFile string, data map[string]string) (ca, cert, key string) {
+	files := []struct {
+		key    string
+		file   string
+		mode   int64
+		target *string
+	}{
+		{PgSslCaKey, caFile, 0o440, &ca},
+		{PgSslCertKey, certFile, 0o440, &cert},
+		{PgSslKeyKey, keyFile, 0o400, &key},
+	}
+
+	for _, f := range files {
+		if len(f.file) > 0 {
+			b, err := os.ReadFile(f.file)
+			checkErr(err)
+			data[f.key] = string(b)
+		}
+		if _, ok := data[f.key]; !ok {
+			continue
+		}
+
+		*f.target = path.Join(helpers.SecretsPath, f.key)
+		prj.AppendHelpers(
+			helpers.Secrets(name, helpers.Secret{
+				Source:   f.key,
+				Target:   *f.target,
+				FileMode: f.mode,
+			}),
+		)
+	}
+
+	return ca, cert, key
+}
+
 type service struct {
-	Name string
-	Type string
+	Name  string
+	Type  string
+	Image string
 }
 
 func (prj *Project) Services() []service {
 	var out []service
 	for k, v := range prj.prj.Services {
 		out = append(out, service{
-			Name: k,
-			Type: v.Labels[compose.ADAppTypeLabelKey],
+			Name:  k,
+			Type:  v.Labels[compose.ADAppTypeLabelKey],
+			Image: v.Image,
 		})
 	}
 
@@ -313,6 +459,13 @@ func setDefaults(config *InitConfig) {
 		config.Adpg.Tag = ADPGTag
 	}
 
+	if len(config.Proxy.Image) == 0 {
+		config.Proxy.Image = ProxyImage
+	}
+	if len(config.Proxy.Tag) == 0 {
+		config.Proxy.Tag = ProxyTag
+	}
+
 	if len(config.Consul.Image) == 0 {
 		config.Consul.Image = ConsulImage
 	}
@@ -322,6 +475,12 @@ func setDefaults(config *InitConfig) {
 	if config.Consul.PublishPort == 0 {
 		config.Consul.PublishPort = ConsulPublishPort
 	}
+	if config.Consul.Count == 0 {
+		config.Consul.Count = 1
+	}
+	if len(config.Consul.Datacenter) == 0 {
+		config.Consul.Datacenter = "dc1"
+	}
 
 	if len(config.Vault.Image) == 0 {
 		config.Vault.Image = VaultImage
@@ -335,6 +494,9 @@ func setDefaults(config *InitConfig) {
 	if len(config.Vault.Mode) == 0 {
 		config.Vault.Mode = VaultDeployModeNonHa
 	}
+	if len(config.Vault.Storage) == 0 {
+		config.Vault.Storage = VaultStoragePostgresql
+	}
 	if len(config.Vault.DBSSLMode) == 0 {
 		config.Vault.DBSSLMode = pgSslModeDisable
 	}
@@ -344,6 +506,21 @@ func setDefaults(config *InitConfig) {
 	if len(config.Vault.DBUser) == 0 {
 		config.Vault.DBUser = "vault"
 	}
+	if config.Vault.Count == 0 {
+		config.Vault.Count = 1
+	}
+	if len(config.Vault.UnsealVia) == 0 {
+		config.Vault.UnsealVia = runner.ViaContainer
+	}
+	if len(config.Vault.Seal) == 0 {
+		config.Vault.Seal = VaultSealTypeShamir
+	}
+	if len(config.Vault.SealTransitKeyName) == 0 {
+		config.Vault.SealTransitKeyName = "autounseal"
+	}
+	if len(config.Vault.SealTransitMountPath) == 0 {
+		config.Vault.SealTransitMountPath = "transit/"
+	}
 	if config.Vault.UI == nil {
 		config.Vault.UI = utils.Ptr(true)
 	}
@@ -358,6 +535,14 @@ func WithCrypt(crypt secrets.Secrets) ProjectOption {
 	}
 }
 
+// WithCA issues the TLS certificates of the services by the CA.
+func WithCA(ca *certs.CA) ProjectOption {
+	return func(p *Project) error {
+		p.ca = ca
+		return nil
+	}
+}
+
 func WithInteractive(b bool) ProjectOption {
 	return func(p *Project) error {
 		p.interactive = b
@@ -379,6 +564,14 @@ func WithConsul(b bool) ProjectOption {
 	}
 }
 
+// WithProxy puts a proxy in front of the ADCM instances.
+func WithProxy(b bool) ProjectOption {
+	return func(p *Project) error {
+		p.config.Proxy.enable = b
+		return nil
+	}
+}
+
 func WithVault(b bool) ProjectOption {
 	return func(p *Project) error {
 		p.config.Vault.enable = b
@@ -16,21 +16,32 @@
 package services
 
 import (
+	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net/url"
 	"os"
 	"path"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/arenadata/adcm-installer/internal/services/helpers"
 	"github.com/arenadata/adcm-installer/pkg/compose"
+	"github.com/arenadata/adcm-installer/pkg/secrets"
 	"github.com/arenadata/adcm-installer/pkg/utils"
+	"github.com/arenadata/adcm-installer/pkg/vault/unseal"
+	"github.com/arenadata/adcm-installer/pkg/vault/unseal/runner"
 
 	"github.com/AlecAivazis/survey/v2"
+	"github.com/Masterminds/semver/v3"
 )
 
+// vaultStaticSealMinVersion is the first OpenBao version with the static seal
+const vaultStaticSealMinVersion = "2.3.0"
+
 type VaultConfig struct {
 	enable bool
 
@@ -47,21 +58,74 @@ type VaultConfig struct {
 	SSLCertFile   string `yaml:"vault-ssl-cert-file"`
 	Image         string `yaml:"vault-image"`
 	Tag           string `yaml:"vault-tag"`
+	Count         uint8  `yaml:"vault-count"`
 	PublishPort   uint16 `yaml:"vault-publish-port"`
 	Mode          string `yaml:"vault-mode"`
+	Storage       string `yaml:"vault-storage"`
+	Volume        string `yaml:"vault-volume"`
 	UI            *bool  `yaml:"vault-ui"`
+
+	KeyShares     uint8    `yaml:"vault-key-shares"`
+	KeyThreshold  uint8    `yaml:"vault-key-threshold"`
+	KeyRecipients []string `yaml:"vault-key-recipients"`
+
+	Seal                 string `yaml:"vault-seal"`
+	SealTransitAddress   string `yaml:"vault-seal-transit-address"`
+	SealTransitToken     string `yaml:"vault-seal-transit-token"`
+	SealTransitKeyName   string `yaml:"vault-seal-transit-key-name"`
+	SealTransitMountPath string `yaml:"vault-seal-transit-mount-path"`
+	SealTransitCaFile    string `yaml:"vault-seal-transit-ca-file"`
+
+	UnsealVia string `yaml:"vault-unseal-via"`
+}
+
+// VaultInit holds the parameters of the Vault initialization, stored on the
+// node which initializes Vault. With KeyRecipients every unseal key share is
+// encrypted for its own age recipient instead of being kept in unseal-data.
+type VaultInit struct {
+	KeyShares     int      `yaml:"key-shares" mapstructure:"key-shares"`
+	KeyThreshold  int      `yaml:"key-threshold" mapstructure:"key-threshold"`
+	KeyRecipients []string `yaml:"key-recipients,omitempty" mapstructure:"key-recipients,omitempty"`
+	UnsealVia     string   `yaml:"unseal-via,omitempty" mapstructure:"unseal-via,omitempty"`
 }
 
 type VaultConfigFile struct {
 	Listener     []map[string]any `json:"listener"`
+	APIAddr      string           `json:"api_addr,omitempty"`
+	ClusterAddr  string           `json:"cluster_addr,omitempty"`
+	Seal         *VaultSeal       `json:"seal,omitempty"`
 	VaultStorage `json:",inline"`
 }
 
+// VaultSeal is the auto-unseal stanza, only one of the seals is set. Vault
+// without the stanza is unsealed with the key shares.
+type VaultSeal struct {
+	Static  *VaultSealStatic  `json:"static,omitempty"`
+	Transit *VaultSealTransit `json:"transit,omitempty"`
+}
+
+type VaultSealStatic struct {
+	CurrentKeyID string `json:"current_key_id"`
+	CurrentKey   string `json:"current_key"`
+}
+
+type VaultSealTransit struct {
+	Address   string `json:"address"`
+	Token     string `json:"token"`
+	KeyName   string `json:"key_name"`
+	MountPath string `json:"mount_path"`
+	CACert    string `json:"tls_ca_cert,omitempty"`
+}
+
 type VaultStorage struct {
 	Storage VaultBackend `json:"storage"`
 }
+
+// VaultBackend is the storage stanza, only one of the backends is set.
 type VaultBackend struct {
-	Postgresql VaultBackendPostgresql `json:"postgresql"`
+	Postgresql *VaultBackendPostgresql `json:"postgresql,omitempty"`
+	Raft       *VaultBackendRaft       `json:"raft,omitempty"`
+	File       *VaultBackendFile       `json:"file,omitempty"`
 }
 
 type VaultBackendPostgresql struct {
@@ -79,15 +143,45 @@ type VaultBackendPostgresql struct {
 	//skip_create_table = false
 }
 
+// VaultBackendRaft is the integrated storage. The nodes of the ha mode join
+// the cluster through retry_join.
+type VaultBackendRaft struct {
+	Path      string           `json:"path"`
+	NodeID    string           `json:"node_id"`
+	RetryJoin []VaultRetryJoin `json:"retry_join,omitempty"`
+}
+
+type VaultRetryJoin struct {
+	LeaderAPIAddr    string `json:"leader_api_addr"`
+	LeaderCACertFile string `json:"leader_ca_cert_file,omitempty"`
+}
+
+type VaultBackendFile struct {
+	Path string `json:"path"`
+}
+
+// vaultCluster holds the values shared by the Vault nodes.
+type vaultCluster struct {
+	config  VaultConfig
+	nodes   []string
+	tls     bool
+	autoTLS bool
+
+	// the client certificate of the managed ADPG database user
+	dbCert string
+	dbKey  string
+
+	// the base64 encoded key of the static seal shared by the nodes
+	sealKey   string
+	sealKeyID string
+}
+
 func (prj *Project) vault() {
 	config := prj.config.Vault
 	if !config.enable {
 		return
 	}
 
-	name := VaultName
-	addService(name, prj.prj)
-
 	managedADPG := prj.config.Adpg.enable
 	if prj.interactive {
 		modePrompt := &prompt{
@@ -97,6 +191,28 @@ func (prj *Project) vault() {
 		}
 		checkErr(readValue(&config.Mode, modePrompt, survey.Required))
 
+		if config.Mode == VaultDeployModeHa {
+			count := strconv.Itoa(int(config.Count))
+			checkErr(readValue(&count, &prompt{msg: "Number of Vault nodes", def: count}))
+			n, err := strconv.ParseUint(count, 10, 8)
+			checkErr(err)
+			config.Count = uint8(n)
+		}
+
+		if config.Mode != VaultDeployModeDev {
+			storagePrompt := &prompt{
+				msg:  "Select Vault storage:",
+				def:  config.Storage,
+				opts: allowVaultStorages,
+			}
+			checkErr(readValue(&config.Storage, storagePrompt, survey.Required))
+
+			if config.Storage != VaultStoragePostgresql {
+				checkErr(readValue(&config.Volume, &prompt{msg: "Vault volume name or path",
+					help: "Leave blank to use a volume named after the node"}))
+			}
+		}
+
 		var ui string
 		opts := []string{"true", "false"}
 		checkErr(readValue(&ui,
@@ -110,14 +226,67 @@ func (prj *Project) vault() {
 		checkErr(readValue(&config.PublishPort, &prompt{msg: "Vault publish port", def: port}))
 	}
 
-	tcpListener := map[string]any{
-		"address": fmt.Sprintf("0.0.0.0:%d", VaultPublishPort),
+	if config.Count == 0 {
+		checkErr(fmt.Errorf("vault-count must be greater than 0"))
+	}
+	if config.Count > 1 && config.Mode != VaultDeployModeHa {
+		checkErr(fmt.Errorf("vault-count %d requires vault-mode %s", config.Count, VaultDeployModeHa))
+	}
+
+	if config.Mode != VaultDeployModeDev {
+		if prj.interactive {
+			shares := strconv.Itoa(int(config.KeyShares))
+			checkErr(readValue(&shares, &prompt{msg: "Number of Vault unseal key shares", def: shares}))
+			n, err := strconv.ParseUint(shares, 10, 8)
+			checkErr(err)
+			config.KeyShares = uint8(n)
+
+			threshold := strconv.Itoa(int(config.KeyThreshold))
+			checkErr(readValue(&threshold,
+				&prompt{msg: "Number of Vault unseal key shares required to unseal", def: threshold}))
+			n, err = strconv.ParseUint(threshold, 10, 8)
+			checkErr(err)
+			config.KeyThreshold = uint8(n)
+		}
+		prj.vaultKeyRecipients(&config)
+		prj.vaultSealConfig(&config)
+
+		if prj.interactive {
+			checkErr(readValue(&config.UnsealVia, &prompt{
+				msg:  "Select how to run the Vault unseal commands:",
+				def:  config.UnsealVia,
+				opts: runner.Backends,
+				help: "The host and api backends use the published port, host requires the vault or bao binary",
+			}, survey.Required))
+		}
+		if !runner.Valid(config.UnsealVia) {
+			checkErr(fmt.Errorf("unknown vault-unseal-via %q", config.UnsealVia))
+		}
+	}
+
+	if !slices.Contains(allowVaultStorages, config.Storage) {
+		checkErr(fmt.Errorf("unknown vault-storage %q", config.Storage))
+	}
+	if config.Storage == VaultStorageFile && config.Mode == VaultDeployModeHa {
+		checkErr(fmt.Errorf("vault-storage %s does not support vault-mode %s", VaultStorageFile, VaultDeployModeHa))
 	}
 
 	config.DBHost = AdpgName
 	config.DBPort = ADPGPublishPort
 
-	if config.Mode != VaultDeployModeDev && (prj.interactive || !managedADPG) {
+	postgres := config.Mode != VaultDeployModeDev && config.Storage == VaultStoragePostgresql
+	if config.Mode != VaultDeployModeDev && prj.interactive && prj.ca == nil {
+		p := &prompt{msg: "Vault SSL Private Key file path:",
+			help: "Leave blank if you do not enable HTTPS"}
+		checkErr(readValue(&config.SSLKeyFile, p, fileExists))
+
+		if len(config.SSLKeyFile) > 0 {
+			checkErr(readValue(&config.SSLCertFile,
+				&prompt{msg: "Vault SSL Certificate file path:"}, fileExists))
+		}
+	}
+
+	if postgres && (prj.interactive || !managedADPG) {
 		if !managedADPG {
 			checkErr(readValue(&config.DBHost,
 				&prompt{msg: "Vault database host:"}, survey.Required))
@@ -151,49 +320,227 @@ func (prj *Project) vault() {
 					&prompt{msg: "Vault database SSL private key file path:"}, fileExists))
 			}
 		}
+	}
 
-		if prj.interactive {
-			p := &prompt{msg: "Vault SSL Private Key file path:",
-				help: "Leave blank if you do not enable HTTPS"}
-			checkErr(readValue(&config.SSLKeyFile, p, fileExists))
-
-			if len(config.SSLKeyFile) > 0 {
-				checkErr(readValue(&config.SSLCertFile,
-					&prompt{msg: "Vault SSL Certificate file path:"}, fileExists))
-
-				keyTarget := path.Join(helpers.SecretsPath, PemKey)
-				tcpListener["tls_key_file"] = keyTarget
-
-				certTarget := path.Join(helpers.SecretsPath, PemCert)
-				tcpListener["tls_cert_file"] = certTarget
-
-				prj.AppendHelpers(
-					helpers.Secrets(name,
-						helpers.Secret{
-							Source:   PemKey,
-							Target:   keyTarget,
-							FileMode: 0o400,
-						},
-						helpers.Secret{
-							Source:   PemCert,
-							Target:   certTarget,
-							FileMode: 0o440,
-						},
-					),
-				)
-			}
+	cluster := &vaultCluster{
+		nodes:   []string{VaultName},
+		autoTLS: prj.ca != nil && config.Mode != VaultDeployModeDev,
+	}
+	if cluster.autoTLS {
+		if managedADPG && postgres {
+			config.DBSSLMode = pgSslModeVerifyFull
+			cluster.dbCert, cluster.dbKey = prj.clientCert(config.DBUser)
 		}
+	} else if config.Mode != VaultDeployModeDev && len(config.SSLKeyFile) > 0 && len(config.SSLCertFile) == 0 {
+		checkErr(fmt.Errorf("vault-ssl-cert-file is required with vault-ssl-key-file"))
 	}
+	cluster.tls = config.Mode != VaultDeployModeDev && (len(config.SSLKeyFile) > 0 || cluster.autoTLS)
 
-	if len(config.SSLKeyFile) == 0 {
-		tcpListener["tls_disable"] = true
+	if postgres && len(config.DBPassword) == 0 {
+		config.DBPassword = utils.GenerateRandomString(16)
 	}
+	cluster.config = config
 
-	if len(config.DBPassword) == 0 {
-		config.DBPassword = utils.GenerateRandomString(16)
+	if config.Mode != VaultDeployModeDev && config.Seal == VaultSealTypeStatic {
+		key := make([]byte, 32)
+		_, err := rand.Read(key)
+		checkErr(err)
+		cluster.sealKey = base64.StdEncoding.EncodeToString(key)
+		cluster.sealKeyID = fmt.Sprintf("%s-%s", prj.prj.Name, time.Now().Format("20060102"))
+	}
+
+	if config.Count > 1 {
+		cluster.nodes = cluster.nodes[:0]
+		for i := uint8(1); i <= config.Count; i++ {
+			cluster.nodes = append(cluster.nodes, fmt.Sprintf("%s-%d", VaultName, i))
+		}
+	}
+
+	for i, name := range cluster.nodes {
+		prj.vaultServer(cluster, i, name)
+	}
+
+	if config.Mode != VaultDeployModeDev {
+		prj.AppendHelpers(helpers.Extension(cluster.nodes[0], VaultInitKey, &VaultInit{
+			KeyShares:     int(config.KeyShares),
+			KeyThreshold:  int(config.KeyThreshold),
+			KeyRecipients: config.KeyRecipients,
+			UnsealVia:     config.UnsealVia,
+		}))
+	}
+}
+
+// vaultSealConfig reads and validates the auto-unseal parameters.
+func (prj *Project) vaultSealConfig(config *VaultConfig) {
+	if prj.interactive {
+		sealPrompt := &prompt{
+			msg:  "Select Vault seal:",
+			def:  config.Seal,
+			opts: allowVaultSeals,
+			help: "The static (OpenBao " + vaultStaticSealMinVersion + " or later) and transit seals unseal Vault automatically after a restart",
+		}
+		checkErr(readValue(&config.Seal, sealPrompt, survey.Required))
+
+		if config.Seal == VaultSealTypeTransit {
+			checkErr(readValue(&config.SealTransitAddress,
+				&prompt{msg: "Transit seal Vault address:", def: config.SealTransitAddress}, survey.Required))
+			checkErr(readValue(&config.SealTransitToken,
+				&prompt{msg: "Transit seal token:", secret: true}, survey.Required))
+			checkErr(readValue(&config.SealTransitKeyName,
+				&prompt{msg: "Transit seal key name:", def: config.SealTransitKeyName}))
+			checkErr(readValue(&config.SealTransitMountPath,
+				&prompt{msg: "Transit seal mount path:", def: config.SealTransitMountPath}))
+			checkErr(readValue(&config.SealTransitCaFile,
+				&prompt{msg: "Transit seal Vault CA file path:", help: "Leave blank to use the system CA"}, fileExists))
+		}
+	}
+
+	if !slices.Contains(allowVaultSeals, config.Seal) {
+		checkErr(fmt.Errorf("unknown vault-seal %q", config.Seal))
+	}
+	if config.Seal == VaultSealTypeStatic && !vaultTagAtLeast(config.Tag, vaultStaticSealMinVersion) {
+		checkErr(fmt.Errorf("vault-seal %s requires OpenBao %s or later, vault-tag is %s",
+			VaultSealTypeStatic, vaultStaticSealMinVersion, config.Tag))
+	}
+	if config.Seal == VaultSealTypeTransit && (len(config.SealTransitAddress) == 0 || len(config.SealTransitToken) == 0) {
+		checkErr(fmt.Errorf("vault-seal-transit-address and vault-seal-transit-token are required with vault-seal %s",
+			VaultSealTypeTransit))
+	}
+}
+
+// vaultTagAtLeast reports whether the image tag is the version or a later one.
+// The tags which are not versions, e.g. latest, are not checked.
+func vaultTagAtLeast(tag, version string) bool {
+	v, err := semver.NewVersion(tag)
+	if err != nil {
+		return true
+	}
+	// the suffix of a tag is a variant of the image, e.g. 2.3.1-ubi
+	release, err := v.SetPrerelease("")
+	if err != nil {
+		return true
+	}
+	return !release.LessThan(semver.MustParse(version))
+}
+
+// vaultSeal returns the auto-unseal stanza of the node or nil for the shamir
+// seal. The static key is mounted as a file, apply decodes it from base64.
+func (prj *Project) vaultSeal(cluster *vaultCluster, name string, data map[string]string) *VaultSeal {
+	config := cluster.config
+
+	switch config.Seal {
+	case VaultSealTypeStatic:
+		target := path.Join(helpers.SecretsPath, VaultSealKey)
+		data[VaultSealKey] = cluster.sealKey
+		prj.AppendHelpers(
+			helpers.Secrets(name, helpers.Secret{Source: VaultSealKey, Target: target, FileMode: 0o400}),
+		)
+
+		return &VaultSeal{Static: &VaultSealStatic{
+			CurrentKeyID: cluster.sealKeyID,
+			CurrentKey:   "file://" + target,
+		}}
+
+	case VaultSealTypeTransit:
+		transit := &VaultSealTransit{
+			Address:   config.SealTransitAddress,
+			Token:     config.SealTransitToken,
+			KeyName:   config.SealTransitKeyName,
+			MountPath: config.SealTransitMountPath,
+		}
+
+		if len(config.SealTransitCaFile) > 0 {
+			b, err := os.ReadFile(config.SealTransitCaFile)
+			checkErr(err)
+			data[VaultSealCa] = string(b)
+
+			transit.CACert = path.Join(helpers.SecretsPath, VaultSealCa)
+			prj.AppendHelpers(
+				helpers.Secrets(name, helpers.Secret{Source: VaultSealCa, Target: transit.CACert, FileMode: 0o440}),
+			)
+		}
+
+		return &VaultSeal{Transit: transit}
+	}
+
+	return nil
+}
+
+// vaultKeyRecipients validates the unseal key shares parameters. The number
+// of shares follows the number of recipients if it is not set.
+func (prj *Project) vaultKeyRecipients(config *VaultConfig) {
+	if prj.interactive && len(config.KeyRecipients) == 0 {
+		var recipients string
+		checkErr(readValue(&recipients, &prompt{msg: "Vault unseal key recipients",
+			help: "Space separated age or ssh public keys, one per share. " +
+				"Leave blank to keep the unseal keys in x-secrets"}))
+		config.KeyRecipients = strings.Fields(recipients)
+	}
+
+	if n := len(config.KeyRecipients); n > 0 {
+		if config.KeyShares == 0 {
+			config.KeyShares = uint8(n)
+		}
+		if n != int(config.KeyShares) {
+			checkErr(fmt.Errorf("vault-key-recipients must have %d recipients, one per share, got %d",
+				config.KeyShares, n))
+		}
+		for i, r := range config.KeyRecipients {
+			var err error
+			config.KeyRecipients[i], err = secrets.NormalizeRecipient(r)
+			checkErr(err)
+		}
+	}
+
+	if config.KeyShares == 0 {
+		config.KeyShares = unseal.DefaultSecretShares
+	}
+	if config.KeyThreshold == 0 {
+		config.KeyThreshold = min(unseal.DefaultSecretThreshold, config.KeyShares)
+	}
+	if config.KeyThreshold > config.KeyShares {
+		checkErr(fmt.Errorf("vault-key-threshold %d must not exceed vault-key-shares %d",
+			config.KeyThreshold, config.KeyShares))
+	}
+	if config.KeyShares > 1 && config.KeyThreshold < 2 {
+		checkErr(fmt.Errorf("vault-key-threshold must be at least 2 with several key shares"))
+	}
+}
+
+// vaultServer adds a Vault node. The nodes of the ha mode share the storage
+// and advertise their own api_addr and cluster_addr.
+func (prj *Project) vaultServer(cluster *vaultCluster, i int, name string) {
+	config := cluster.config
+	addService(name, prj.prj)
+
+	managedADPG := prj.config.Adpg.enable
+	hostname := prj.hostname(name)
+
+	tcpListener := map[string]any{
+		"address": fmt.Sprintf("0.0.0.0:%d", VaultPublishPort),
+	}
+
+	if cluster.autoTLS {
+		prj.vaultTLS(name, tcpListener)
+		prj.AppendHelpers(
+			helpers.Secrets(name, helpers.Secret{
+				Source:   PemCa,
+				Target:   path.Join(helpers.SecretsPath, PemCa),
+				FileMode: 0o440,
+			}),
+			helpers.Environment(name,
+				helpers.Env{Name: "BAO_CACERT", Value: utils.Ptr(path.Join(helpers.SecretsPath, PemCa))}),
+		)
+	} else if cluster.tls {
+		prj.vaultTLS(name, tcpListener)
+	}
+
+	if !cluster.tls {
+		tcpListener["tls_disable"] = true
 	}
 
-	if managedADPG {
+	postgres := config.Mode != VaultDeployModeDev && config.Storage == VaultStoragePostgresql
+	if managedADPG && postgres {
 		prj.AppendHelpers(
 			helpers.DependsOn(name,
 				helpers.Depended{
@@ -211,14 +558,16 @@ func (prj *Project) vault() {
 			),
 		)
 	} else {
-		baoAddr := "http://127.0.0.1:8200/"
+		scheme := "http"
 		var wgetArg string
-		if len(config.SSLKeyFile) > 0 {
-			baoAddr = "https://127.0.0.1:8200/"
+		if cluster.tls {
+			scheme = "https"
 			wgetArg = " --no-check-certificate"
 		}
+		baoAddr := fmt.Sprintf("%s://127.0.0.1:%d/", scheme, VaultPublishPort)
 
-		healthCheckCommand := fmt.Sprintf("wget%s -q -O - %sv1/sys/health", wgetArg, baoAddr)
+		// a standby node is healthy as well
+		healthCheckCommand := fmt.Sprintf("wget%s -q -O - '%sv1/sys/health?standbyok=true'", wgetArg, baoAddr)
 		prj.AppendHelpers(
 			helpers.Environment(name,
 				helpers.Env{Name: "BAO_ADDR", Value: &baoAddr},
@@ -231,80 +580,50 @@ func (prj *Project) vault() {
 			}),
 		)
 
-		unMappedSecrets := map[string]string{
-			PgDbName: config.DBName,
-			PgDbUser: config.DBUser,
-			PgDbPass: config.DBPassword,
-		}
+		unMappedSecrets := map[string]string{}
 		xsecretsData := map[string]string{}
 
-		params := url.Values{}
-		params.Set("sslmode", config.DBSSLMode)
-
-		if config.DBSSLMode != pgSslModeDisable {
-			if len(config.DBSSLCaFile) > 0 {
-				b, err := os.ReadFile(config.DBSSLCaFile)
-				checkErr(err)
-				xsecretsData[PgSslCaKey] = string(b)
-
-				target := path.Join(helpers.SecretsPath, PgSslCaKey)
-				params.Set("sslrootcert", target)
-				prj.AppendHelpers(
-					helpers.Secrets(name, helpers.Secret{
-						Source:   PgSslCaKey,
-						Target:   target,
-						FileMode: 0o440,
-					}),
-				)
-			}
-			if len(config.DBSSLCertFile) > 0 {
-				b, err := os.ReadFile(config.DBSSLCertFile)
-				checkErr(err)
-				xsecretsData[PgSslCertKey] = string(b)
-
-				target := path.Join(helpers.SecretsPath, PgSslCertKey)
-				params.Set("sslcert", target)
-				prj.AppendHelpers(
-					helpers.Secrets(name, helpers.Secret{
-						Source:   PgSslCertKey,
-						Target:   target,
-						FileMode: 0o440,
-					}),
-				)
-			}
-			if len(config.DBSSLKeyFile) > 0 {
-				b, err := os.ReadFile(config.DBSSLKeyFile)
-				checkErr(err)
-				xsecretsData[PgSslKeyKey] = string(b)
-
-				target := path.Join(helpers.SecretsPath, PgSslKeyKey)
-				params.Set("sslkey", target)
-				prj.AppendHelpers(
-					helpers.Secrets(name, helpers.Secret{
-						Source:   PgSslKeyKey,
-						Target:   target,
-						FileMode: 0o400,
-					}),
-				)
-			}
+		if cluster.autoTLS {
+			xsecretsData[PemCa] = prj.ca.CertPEM()
+			xsecretsData[PemCert], xsecretsData[PemKey] = prj.serverCert(name)
 		}
 
-		u := &url.URL{
-			Scheme:   "postgres",
-			Host:     fmt.Sprintf("%s:%d", config.DBHost, config.DBPort),
-			RawQuery: params.Encode(),
+		vaultConfig := VaultConfigFile{
+			Listener: []map[string]any{{"tcp": tcpListener}},
+			Seal:     prj.vaultSeal(cluster, name, xsecretsData),
 		}
 
-		backend := VaultBackendPostgresql{
-			ConnectionUrl: u.String(),
-			HA:            config.Mode == VaultDeployModeHa,
+		switch config.Storage {
+		case VaultStoragePostgresql:
+			backend := prj.vaultPostgresql(cluster, name, xsecretsData, unMappedSecrets)
+			vaultConfig.Storage.Postgresql = backend
+		case VaultStorageRaft:
+			backend := &VaultBackendRaft{Path: VaultDataMountPath, NodeID: hostname}
+			for _, node := range cluster.nodes {
+				if node == name {
+					continue
+				}
+				join := VaultRetryJoin{
+					LeaderAPIAddr: fmt.Sprintf("%s://%s:%d", scheme, prj.hostname(node), VaultPublishPort),
+				}
+				if cluster.autoTLS {
+					join.LeaderCACertFile = path.Join(helpers.SecretsPath, PemCa)
+				}
+				backend.RetryJoin = append(backend.RetryJoin, join)
+			}
+			vaultConfig.Storage.Raft = backend
+			prj.vaultVolume(cluster, i, name)
+		case VaultStorageFile:
+			vaultConfig.Storage.File = &VaultBackendFile{Path: VaultDataMountPath}
+			prj.vaultVolume(cluster, i, name)
 		}
 
-		vaultConfig := VaultConfigFile{
-			Listener: []map[string]any{{"tcp": tcpListener}},
-			VaultStorage: VaultStorage{
-				Storage: VaultBackend{Postgresql: backend},
-			},
+		// the integrated storage requires the cluster addresses even with a
+		// single node
+		if config.Mode == VaultDeployModeHa || config.Storage == VaultStorageRaft {
+			tcpListener["cluster_address"] = fmt.Sprintf("0.0.0.0:%d", VaultClusterPort)
+			vaultConfig.APIAddr = fmt.Sprintf("%s://%s:%d", scheme, hostname, VaultPublishPort)
+			vaultConfig.ClusterAddr = fmt.Sprintf("https://%s:%d", hostname, VaultClusterPort)
 		}
 
 		b, err := json.Marshal(vaultConfig)
@@ -333,13 +652,13 @@ func (prj *Project) vault() {
 		if prj.crypt != nil {
 			var err error
 			for k, v := range xsecretsData {
-				v, err = prj.crypt.EncryptValue(v)
+				v, err = prj.crypt.EncryptValue(v, name, k)
 				checkErr(err)
 				xsecretsData[k] = v
 			}
 
 			for k, v := range unMappedSecrets {
-				v, err = prj.crypt.EncryptValue(v)
+				v, err = prj.crypt.EncryptValue(v, name, k)
 				checkErr(err)
 				unMappedSecrets[k] = v
 			}
@@ -349,7 +668,7 @@ func (prj *Project) vault() {
 	}
 
 	prj.AppendHelpers(
-		helpers.Hostname(name, prj.hostname(name)),
+		helpers.Hostname(name, hostname),
 		helpers.Image(name, config.Image+":"+config.Tag),
 		helpers.Labels(name, map[string]string{
 			compose.ADAppTypeLabelKey:   VaultName,
@@ -364,6 +683,84 @@ func (prj *Project) vault() {
 	}
 
 	if config.PublishPort > 0 {
-		prj.AppendHelpers(helpers.PublishPort(VaultName, config.PublishPort, VaultPublishPort))
+		prj.AppendHelpers(helpers.PublishPort(name, config.PublishPort+uint16(i), VaultPublishPort))
+	}
+}
+
+// vaultPostgresql returns the postgresql storage backend. The database
+// credentials are kept in unMapped and set to the connection url by apply.
+func (prj *Project) vaultPostgresql(cluster *vaultCluster, name string, data, unMapped map[string]string) *VaultBackendPostgresql {
+	config := cluster.config
+
+	unMapped[PgDbName] = config.DBName
+	unMapped[PgDbUser] = config.DBUser
+	unMapped[PgDbPass] = config.DBPassword
+
+	params := url.Values{}
+	params.Set("sslmode", config.DBSSLMode)
+
+	if cluster.autoTLS && prj.config.Adpg.enable {
+		data[PgSslCaKey] = prj.ca.CertPEM()
+		data[PgSslCertKey], data[PgSslKeyKey] = cluster.dbCert, cluster.dbKey
+	}
+
+	if config.DBSSLMode != pgSslModeDisable {
+		ca, cert, key := prj.dbSSLSecrets(name,
+			config.DBSSLCaFile, config.DBSSLCertFile, config.DBSSLKeyFile, data)
+		for k, v := range map[string]string{"sslrootcert": ca, "sslcert": cert, "sslkey": key} {
+			if len(v) > 0 {
+				params.Set(k, v)
+			}
+		}
+	}
+
+	u := &url.URL{
+		Scheme:   "postgres",
+		Host:     fmt.Sprintf("%s:%d", config.DBHost, config.DBPort),
+		RawQuery: params.Encode(),
+	}
+
+	return &VaultBackendPostgresql{
+		ConnectionUrl: u.String(),
+		HA:            config.Mode == VaultDeployModeHa,
+	}
+}
+
+// vaultVolume mounts the data volume of the raft and file storages.
+func (prj *Project) vaultVolume(cluster *vaultCluster, i int, name string) {
+	config := cluster.config
+
+	volume := prj.hostname(name)
+	if len(config.Volume) > 0 {
+		volume = config.Volume
+		if len(cluster.nodes) > 1 {
+			volume = fmt.Sprintf("%s-%d", config.Volume, i+1)
+		}
 	}
+
+	prj.AppendHelpers(helpers.Volumes(name, volume+":"+VaultDataMountPath))
+}
+
+// vaultTLS enables TLS of the listener with the certificate mounted to Vault.
+func (prj *Project) vaultTLS(name string, tcpListener map[string]any) {
+	keyTarget := path.Join(helpers.SecretsPath, PemKey)
+	tcpListener["tls_key_file"] = keyTarget
+
+	certTarget := path.Join(helpers.SecretsPath, PemCert)
+	tcpListener["tls_cert_file"] = certTarget
+
+	prj.AppendHelpers(
+		helpers.Secrets(name,
+			helpers.Secret{
+				Source:   PemKey,
+				Target:   keyTarget,
+				FileMode: 0o400,
+			},
+			helpers.Secret{
+				Source:   PemCert,
+				Target:   certTarget,
+				FileMode: 0o440,
+			},
+		),
+	)
 }
@@ -0,0 +1,331 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	ManifestName    = "manifest.json"
+	ManifestVersion = 1
+)
+
+type File struct {
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+type Image struct {
+	Image  string `json:"image"`
+	Digest string `json:"digest,omitempty"`
+}
+
+type Volume struct {
+	Service string `json:"service"`
+	Key     string `json:"key,omitempty"`
+	Type    string `json:"type"`
+	Source  string `json:"source"`
+	File    string `json:"file"`
+}
+
+type Manifest struct {
+	Version    int              `json:"version"`
+	AdiVersion string           `json:"adi_version"`
+	Created    time.Time        `json:"created"`
+	Project    string           `json:"project"`
+	Images     map[string]Image `json:"images,omitempty"`
+	Volumes    []Volume         `json:"volumes,omitempty"`
+	Files      map[string]File  `json:"files"`
+}
+
+// Archive is a backup staged in a temporary directory. Files are added with
+// Create and packed into a gzipped tarball by Write, the manifest goes first.
+type Archive struct {
+	Manifest Manifest
+
+	dir string
+}
+
+func New(project, adiVersion string) (*Archive, error) {
+	dir, err := os.MkdirTemp("", "adi-backup-*")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Archive{
+		Manifest: Manifest{
+			Version:    ManifestVersion,
+			AdiVersion: adiVersion,
+			Created:    time.Now().UTC(),
+			Project:    project,
+			Images:     map[string]Image{},
+			Files:      map[string]File{},
+		},
+		dir: dir,
+	}, nil
+}
+
+func checkName(name string) error {
+	if name == ManifestName || !filepath.IsLocal(name) || path.Clean(name) != name {
+		return fmt.Errorf("invalid archive file name %q", name)
+	}
+	return nil
+}
+
+type fileWriter struct {
+	f    *os.File
+	h    hash.Hash
+	size int64
+	done func(File)
+}
+
+func (w *fileWriter) Write(p []byte) (int, error) {
+	n, err := w.f.Write(p)
+	w.h.Write(p[:n])
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *fileWriter) Close() error {
+	if err := w.f.Close(); err != nil {
+		return err
+	}
+	w.done(File{SHA256: hex.EncodeToString(w.h.Sum(nil)), Size: w.size})
+	return nil
+}
+
+// Create adds the file to the archive. The checksum is recorded in the
+// manifest when the returned writer is closed.
+func (a *Archive) Create(name string) (io.WriteCloser, error) {
+	if err := checkName(name); err != nil {
+		return nil, err
+	}
+
+	p := filepath.Join(a.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(p, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fileWriter{
+		f: f,
+		h: sha256.New(),
+		done: func(file File) {
+			a.Manifest.Files[name] = file
+		},
+	}, nil
+}
+
+func (a *Archive) Open(name string) (*os.File, error) {
+	if err := checkName(name); err != nil {
+		return nil, err
+	}
+	if _, ok := a.Manifest.Files[name]; !ok {
+		return nil, fmt.Errorf("%s: %w", name, os.ErrNotExist)
+	}
+	return os.Open(filepath.Join(a.dir, filepath.FromSlash(name)))
+}
+
+func (a *Archive) ReadFile(name string) ([]byte, error) {
+	f, err := a.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	return io.ReadAll(f)
+}
+
+// Write packs the manifest and all the staged files into w.
+func (a *Archive) Write(w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	manifest, err := json.MarshalIndent(a.Manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	hdr := &tar.Header{
+		Name:    ManifestName,
+		Mode:    0600,
+		Size:    int64(len(manifest)),
+		ModTime: a.Manifest.Created,
+	}
+	if err = tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if _, err = tw.Write(manifest); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(a.Manifest.Files))
+	for name := range a.Manifest.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err = a.writeFile(tw, name); err != nil {
+			return err
+		}
+	}
+
+	if err = tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func (a *Archive) writeFile(tw *tar.Writer, name string) error {
+	f, err := os.Open(filepath.Join(a.dir, filepath.FromSlash(name)))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    a.Manifest.Files[name].Size,
+		ModTime: a.Manifest.Created,
+	}
+	if err = tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// Read extracts the archive into a temporary directory and verifies the
+// files against the manifest checksums.
+func Read(r io.Reader) (_ *Archive, err error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = gz.Close() }()
+
+	dir, err := os.MkdirTemp("", "adi-restore-*")
+	if err != nil {
+		return nil, err
+	}
+	a := &Archive{dir: dir}
+	defer func() {
+		if err != nil {
+			_ = a.Close()
+		}
+	}()
+
+	tr := tar.NewReader(gz)
+	hdr, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("reading manifest: %w", err)
+	}
+	if hdr.Name != ManifestName {
+		return nil, errors.New("not an adi backup: manifest not found")
+	}
+	if err = json.NewDecoder(tr).Decode(&a.Manifest); err != nil {
+		return nil, fmt.Errorf("reading manifest: %w", err)
+	}
+	if a.Manifest.Version != ManifestVersion {
+		return nil, fmt.Errorf("unsupported backup version %d", a.Manifest.Version)
+	}
+
+	seen := map[string]bool{}
+	for {
+		hdr, err = tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if err = a.extract(hdr, tr); err != nil {
+			return nil, err
+		}
+		seen[hdr.Name] = true
+	}
+
+	var missing []string
+	for name := range a.Manifest.Files {
+		if !seen[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("missing files in archive: %s", strings.Join(missing, ", "))
+	}
+
+	return a, nil
+}
+
+func (a *Archive) extract(hdr *tar.Header, r io.Reader) error {
+	want, ok := a.Manifest.Files[hdr.Name]
+	if !ok {
+		return fmt.Errorf("%s: not in manifest", hdr.Name)
+	}
+	if err := checkName(hdr.Name); err != nil {
+		return err
+	}
+
+	p := filepath.Join(a.dir, filepath.FromSlash(hdr.Name))
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(p, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(f, h), r)
+	if err != nil {
+		return err
+	}
+
+	if n != want.Size || hex.EncodeToString(h.Sum(nil)) != want.SHA256 {
+		return fmt.Errorf("%s: checksum mismatch", hdr.Name)
+	}
+
+	return nil
+}
+
+func (a *Archive) Close() error {
+	return os.RemoveAll(a.dir)
+}
@@ -0,0 +1,117 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package backup
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func writeArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	a, err := New("demo", "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = a.Close() }()
+
+	for name, data := range files {
+		w, err := a.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = io.WriteString(w, data); err != nil {
+			t.Fatal(err)
+		}
+		if err = w.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	if err = a.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestArchive(t *testing.T) {
+	files := map[string]string{
+		"adcm.yaml":          "name: demo\n",
+		"adpg.sql":           "SELECT 1;\n",
+		"volumes/adcm.tar":   "data",
+		"volumes/consul.tar": "",
+	}
+
+	a, err := Read(bytes.NewReader(writeArchive(t, files)))
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	defer func() { _ = a.Close() }()
+
+	if a.Manifest.Project != "demo" {
+		t.Errorf("Read() project = %v, want %v", a.Manifest.Project, "demo")
+	}
+	for name, want := range files {
+		got, err := a.ReadFile(name)
+		if err != nil {
+			t.Errorf("ReadFile(%s) error = %v", name, err)
+			continue
+		}
+		if string(got) != want {
+			t.Errorf("ReadFile(%s) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestArchiveCorrupted(t *testing.T) {
+	files := map[string]string{"adpg.sql": "SELECT 1;\n"}
+
+	tests := []struct {
+		name string
+		arg  func() []byte
+	}{
+		{"Truncated", func() []byte {
+			b := writeArchive(t, files)
+			return b[:len(b)/2]
+		}},
+		{"Garbage", func() []byte { return []byte("not a backup") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if a, err := Read(bytes.NewReader(tt.arg())); err == nil {
+				_ = a.Close()
+				t.Errorf("Read() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestCreateInvalidName(t *testing.T) {
+	a, err := New("demo", "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = a.Close() }()
+
+	for _, name := range []string{ManifestName, "../adcm.yaml", "/etc/passwd", "a/../b"} {
+		if _, err = a.Create(name); err == nil {
+			t.Errorf("Create(%s) error = nil, want error", name)
+		}
+	}
+}
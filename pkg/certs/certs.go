@@ -0,0 +1,279 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package certs
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"time"
+)
+
+const (
+	CATTL   = 10 * 365 * 24 * time.Hour
+	CertTTL = 365 * 24 * time.Hour
+
+	// RenewBefore is the default period before the expiration in which
+	// certificates are reissued.
+	RenewBefore = 30 * 24 * time.Hour
+
+	pemTypeCertificate = "CERTIFICATE"
+	pemTypeECKey       = "EC PRIVATE KEY"
+)
+
+// Usage is the extended key usage of an issued certificate.
+type Usage int
+
+const (
+	UsageServer Usage = iota
+	UsageClient
+	// UsagePeer certificates authenticate both ends of a connection, e.g.
+	// between the members of a cluster.
+	UsagePeer
+)
+
+type Request struct {
+	CommonName string
+	DNSNames   []string
+	IPs        []net.IP
+	Usage      Usage
+}
+
+// CA issues the certificates of a project.
+type CA struct {
+	Cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+
+	certPEM []byte
+}
+
+func NewCA(commonName string) (*CA, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return newCA(commonName, key)
+}
+
+func newCA(commonName string, key *ecdsa.PrivateKey) (*CA, error) {
+	serial, err := serialNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	skid, err := subjectKeyId(key.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             now.Add(-time.Minute),
+		NotAfter:              now.Add(CATTL),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+		SubjectKeyId:          skid,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CA{
+		Cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: pemTypeCertificate, Bytes: der}),
+	}, nil
+}
+
+// ParseCA reads the CA stored as PEM.
+func ParseCA(certPEM, keyPEM string) (*CA, error) {
+	cert, err := ParseCertificate(certPEM)
+	if err != nil {
+		return nil, err
+	}
+	if !cert.IsCA {
+		return nil, errors.New("certificate is not a CA")
+	}
+
+	key, err := parseKey(keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	if !key.PublicKey.Equal(cert.PublicKey) {
+		return nil, errors.New("CA key does not match the certificate")
+	}
+
+	return &CA{Cert: cert, key: key, certPEM: []byte(certPEM)}, nil
+}
+
+func (ca *CA) CertPEM() string {
+	return string(ca.certPEM)
+}
+
+func (ca *CA) KeyPEM() (string, error) {
+	return encodeKey(ca.key)
+}
+
+// Renew reissues the CA certificate with the same key, so certificates issued
+// before stay valid.
+func (ca *CA) Renew() (*CA, error) {
+	return newCA(ca.Cert.Subject.CommonName, ca.key)
+}
+
+// Issue returns the PEM encoded certificate and private key.
+func (ca *CA) Issue(req Request) (string, string, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+
+	serial, err := serialNumber()
+	if err != nil {
+		return "", "", err
+	}
+
+	extKeyUsage := []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	switch req.Usage {
+	case UsageClient:
+		extKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	case UsagePeer:
+		extKeyUsage = append(extKeyUsage, x509.ExtKeyUsageClientAuth)
+	}
+
+	now := time.Now()
+	notAfter := now.Add(CertTTL)
+	if notAfter.After(ca.Cert.NotAfter) {
+		notAfter = ca.Cert.NotAfter
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: req.CommonName},
+		DNSNames:     req.DNSNames,
+		IPAddresses:  req.IPs,
+		NotBefore:    now.Add(-time.Minute),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  extKeyUsage,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.Cert, key.Public(), ca.key)
+	if err != nil {
+		return "", "", err
+	}
+
+	keyPEM, err := encodeKey(key)
+	if err != nil {
+		return "", "", err
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: pemTypeCertificate, Bytes: der})), keyPEM, nil
+}
+
+// Reissue issues a new certificate with the subject, names and usage of the
+// given one.
+func (ca *CA) Reissue(certPEM string) (string, string, error) {
+	cert, err := ParseCertificate(certPEM)
+	if err != nil {
+		return "", "", err
+	}
+
+	req := Request{
+		CommonName: cert.Subject.CommonName,
+		DNSNames:   cert.DNSNames,
+		IPs:        cert.IPAddresses,
+	}
+	var server, client bool
+	for _, u := range cert.ExtKeyUsage {
+		server = server || u == x509.ExtKeyUsageServerAuth
+		client = client || u == x509.ExtKeyUsageClientAuth
+	}
+	switch {
+	case server && client:
+		req.Usage = UsagePeer
+	case client:
+		req.Usage = UsageClient
+	}
+
+	return ca.Issue(req)
+}
+
+// Issued reports whether the certificate is signed by the CA.
+func (ca *CA) Issued(cert *x509.Certificate) bool {
+	return !cert.IsCA && cert.CheckSignatureFrom(ca.Cert) == nil
+}
+
+func ParseCertificate(certPEM string) (*x509.Certificate, error) {
+	block, _ := pem.Decode([]byte(certPEM))
+	if block == nil || block.Type != pemTypeCertificate {
+		return nil, errors.New("no PEM encoded certificate found")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// ExpiresWithin reports whether the certificate expires before now + d.
+func ExpiresWithin(cert *x509.Certificate, d time.Duration) bool {
+	return time.Now().Add(d).After(cert.NotAfter)
+}
+
+func parseKey(keyPEM string) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(keyPEM))
+	if block == nil || block.Type != pemTypeECKey {
+		return nil, errors.New("no PEM encoded EC private key found")
+	}
+	return x509.ParseECPrivateKey(block.Bytes)
+}
+
+func encodeKey(key *ecdsa.PrivateKey) (string, error) {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: pemTypeECKey, Bytes: der})), nil
+}
+
+func serialNumber() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+func subjectKeyId(pub crypto.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, fmt.Errorf("marshal public key: %v", err)
+	}
+	sum := sha1.Sum(der)
+	return sum[:], nil
+}
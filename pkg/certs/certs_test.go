@@ -0,0 +1,118 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package certs
+
+import (
+	"crypto/x509"
+	"testing"
+)
+
+func verify(t *testing.T, ca *CA, certPEM, dnsName string, usage x509.ExtKeyUsage) error {
+	t.Helper()
+
+	cert, err := ParseCertificate(certPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Cert)
+	_, err = cert.Verify(x509.VerifyOptions{Roots: roots, DNSName: dnsName, KeyUsages: []x509.ExtKeyUsage{usage}})
+	return err
+}
+
+func TestIssue(t *testing.T) {
+	ca, err := NewCA("demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		req     Request
+		dnsName string
+		usage   x509.ExtKeyUsage
+		wantErr bool
+	}{
+		{"Server", Request{CommonName: "demo-adpg", DNSNames: []string{"demo-adpg", "adpg"}}, "adpg",
+			x509.ExtKeyUsageServerAuth, false},
+		{"WrongName", Request{CommonName: "demo-adpg", DNSNames: []string{"demo-adpg"}}, "demo-vault",
+			x509.ExtKeyUsageServerAuth, true},
+		{"Client", Request{CommonName: "adcm", Usage: UsageClient}, "",
+			x509.ExtKeyUsageClientAuth, false},
+		{"ClientAsServer", Request{CommonName: "adcm", Usage: UsageClient}, "",
+			x509.ExtKeyUsageServerAuth, true},
+		{"PeerAsServer", Request{CommonName: "consul-1", DNSNames: []string{"consul-1"}, Usage: UsagePeer},
+			"consul-1", x509.ExtKeyUsageServerAuth, false},
+		{"PeerAsClient", Request{CommonName: "consul-1", Usage: UsagePeer}, "",
+			x509.ExtKeyUsageClientAuth, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			certPEM, _, err := ca.Issue(tt.req)
+			if err != nil {
+				t.Fatalf("Issue() error = %v", err)
+			}
+			if err = verify(t, ca, certPEM, tt.dnsName, tt.usage); (err != nil) != tt.wantErr {
+				t.Errorf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRenew(t *testing.T) {
+	ca, err := NewCA("demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certPEM, _, err := ca.Issue(Request{CommonName: "demo-vault", DNSNames: []string{"demo-vault"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newCertPEM, _, err := ca.Reissue(certPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = verify(t, ca, newCertPEM, "demo-vault", x509.ExtKeyUsageServerAuth); err != nil {
+		t.Errorf("reissued certificate: %v", err)
+	}
+
+	renewed, err := ca.Renew()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = verify(t, renewed, certPEM, "demo-vault", x509.ExtKeyUsageServerAuth); err != nil {
+		t.Errorf("certificate issued by the previous CA certificate: %v", err)
+	}
+
+	keyPEM, err := renewed.KeyPEM()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ParseCA(renewed.CertPEM(), keyPEM); err != nil {
+		t.Errorf("ParseCA() error = %v", err)
+	}
+
+	other, err := NewCA("other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ParseCA(other.CertPEM(), keyPEM); err == nil {
+		t.Errorf("ParseCA() accepted a key of another CA")
+	}
+}
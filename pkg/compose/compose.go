@@ -18,6 +18,7 @@ package compose
 import (
 	"context"
 	"fmt"
+	"io"
 	"sort"
 	"strings"
 	"time"
@@ -32,6 +33,8 @@ import (
 	containerTypes "github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/system"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/jsonmessage"
 	"github.com/sirupsen/logrus"
 )
 
@@ -39,6 +42,9 @@ const (
 	ADLabel             = "app.arenadata.io"
 	ADAppTypeLabelKey   = ADLabel + "/type"
 	ADVaultModeLabelKey = ADLabel + "/vault-mode"
+	// ADJobLabelKey marks the init jobs, its value is the marker file of the
+	// job in its first volume, if any
+	ADJobLabelKey = ADLabel + "/job"
 
 	DefaultNetwork  = "default"
 	DefaultPlatform = "linux/amd64"
@@ -85,10 +91,41 @@ func (c Compose) Remove(ctx context.Context, prj *types.Project, services ...str
 	})
 }
 
-func (c Compose) Up(ctx context.Context, prj *types.Project, wait bool) error {
+type UpOption func(*api.UpOptions)
+
+// WithRemoveOrphans removes containers of services missing in the project.
+func WithRemoveOrphans() UpOption {
+	return func(o *api.UpOptions) {
+		o.Create.RemoveOrphans = true
+	}
+}
+
+// WithForceRecreate recreates the containers of the services even if their
+// configuration has not changed, the other services are recreated only if it
+// has.
+func WithForceRecreate(services ...string) UpOption {
+	return func(o *api.UpOptions) {
+		if len(services) == 0 {
+			return
+		}
+		o.Create.Services = services
+		o.Create.Recreate = api.RecreateForce
+		o.Create.RecreateDependencies = api.RecreateDiverged
+	}
+}
+
+// WithWaitTimeout sets how long to wait for the services to become healthy,
+// or running if they have no health check.
+func WithWaitTimeout(timeout time.Duration) UpOption {
+	return func(o *api.UpOptions) {
+		o.Start.WaitTimeout = timeout
+	}
+}
+
+func (c Compose) Up(ctx context.Context, prj *types.Project, wait bool, opts ...UpOption) error {
 	timeout := 30 * time.Second
 
-	return c.svc.Up(ctx, prj, api.UpOptions{
+	options := api.UpOptions{
 		Create: api.CreateOptions{
 			Timeout:              &timeout,
 			Recreate:             "diverged",
@@ -99,7 +136,16 @@ func (c Compose) Up(ctx context.Context, prj *types.Project, wait bool) error {
 			Wait:        wait,
 			WaitTimeout: timeout,
 		},
-	})
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return c.svc.Up(ctx, prj, options)
+}
+
+func (c Compose) Pull(ctx context.Context, prj *types.Project) error {
+	return c.svc.Pull(ctx, prj, api.PullOptions{})
 }
 
 func (c Compose) Down(ctx context.Context, prj *types.Project, volumes bool) error {
@@ -118,6 +164,21 @@ func (c Compose) Down(ctx context.Context, prj *types.Project, volumes bool) err
 	})
 }
 
+func (c Compose) ImageLoad(ctx context.Context, r io.Reader) error {
+	resp, err := c.cli.Client().ImageLoad(ctx, r, client.ImageLoadWithQuiet(true))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	// the load errors are reported in the response stream
+	return jsonmessage.DisplayJSONMessagesStream(resp.Body, io.Discard, 0, false, nil)
+}
+
+func (c Compose) ImageTag(ctx context.Context, source, target string) error {
+	return c.cli.Client().ImageTag(ctx, source, target)
+}
+
 func (c Compose) Info(ctx context.Context) (system.Info, error) {
 	return c.cli.Client().Info(ctx)
 }
@@ -137,6 +198,25 @@ func (c Compose) List(ctx context.Context, all bool) ([]container.Summary, error
 	)
 }
 
+// ProjectContainers lists all the containers of the project, including
+// stopped and init ones.
+func (c Compose) ProjectContainers(ctx context.Context, prjName string) ([]container.Summary, error) {
+	return c.list(ctx, true,
+		filters.Arg("label", api.ProjectLabel+"="+prjName),
+		filters.Arg("label", ADLabel),
+	)
+}
+
+func (c Compose) Inspect(ctx context.Context, containerID string) (container.InspectResponse, error) {
+	return c.cli.Client().ContainerInspect(ctx, containerID)
+}
+
+// ServiceHash returns the configuration hash docker compose sets on the
+// service containers.
+func ServiceHash(svc types.ServiceConfig) (string, error) {
+	return compose.ServiceHash(svc)
+}
+
 func (c Compose) ListProjects(ctx context.Context, all bool) ([]api.Stack, error) {
 	list, err := c.List(ctx, all)
 	if err != nil {
@@ -0,0 +1,84 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package compose
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/docker/compose/v2/pkg/api"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+)
+
+// Event is a state change of a project container.
+type Event struct {
+	Action    events.Action
+	ID        string
+	Container string
+	Service   string
+	AppType   string
+	// ExitCode is set by the die events
+	ExitCode int
+}
+
+// Events streams the events of the project containers until ctx is done or
+// the stream fails, the error is sent to the error channel then.
+func (c Compose) Events(ctx context.Context, prjName string) (<-chan Event, <-chan error) {
+	msgs, errs := c.cli.Client().Events(ctx, events.ListOptions{
+		Filters: filters.NewArgs(
+			filters.Arg("type", string(events.ContainerEventType)),
+			filters.Arg("label", api.ProjectLabel+"="+prjName),
+			filters.Arg("label", ADLabel),
+		),
+	})
+
+	out := make(chan Event)
+	outErrs := make(chan error, 1)
+	go func() {
+		defer close(out)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case err := <-errs:
+				outErrs <- err
+				return
+			case msg := <-msgs:
+				attrs := msg.Actor.Attributes
+				ev := Event{
+					Action:    msg.Action,
+					ID:        msg.Actor.ID,
+					Container: attrs["name"],
+					Service:   attrs[api.ServiceLabel],
+					AppType:   attrs[ADAppTypeLabelKey],
+				}
+				if code, ok := attrs["exitCode"]; ok {
+					ev.ExitCode, _ = strconv.Atoi(code)
+				}
+
+				select {
+				case out <- ev:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return out, outErrs
+}
@@ -0,0 +1,76 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package compose
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// ExecStream runs a command in a running container. stdin is streamed to the
+// command if set, its stdout is copied to stdout.
+func (c Compose) ExecStream(ctx context.Context, containerName string, env, cmd []string, stdin io.Reader, stdout io.Writer) error {
+	cli := c.cli.Client()
+
+	exec, err := cli.ContainerExecCreate(ctx, containerName, container.ExecOptions{
+		Cmd:          cmd,
+		Env:          env,
+		AttachStdin:  stdin != nil,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := cli.ContainerExecAttach(ctx, exec.ID, container.ExecAttachOptions{})
+	if err != nil {
+		return err
+	}
+	defer resp.Close()
+
+	if stdin != nil {
+		go func() {
+			_, _ = io.Copy(resp.Conn, stdin)
+			_ = resp.CloseWrite()
+		}()
+	}
+
+	if stdout == nil {
+		stdout = io.Discard
+	}
+
+	stderr := new(bytes.Buffer)
+	if _, err = stdcopy.StdCopy(stdout, stderr, resp.Reader); err != nil {
+		return err
+	}
+
+	inspect, err := cli.ContainerExecInspect(ctx, exec.ID)
+	if err != nil {
+		return err
+	}
+	if inspect.ExitCode != 0 {
+		return fmt.Errorf("%s: exit code %d: %s", cmd[0], inspect.ExitCode, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
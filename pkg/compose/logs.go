@@ -0,0 +1,132 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package compose
+
+import (
+	"context"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/docker/compose/v2/pkg/api"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+const redacted = "******"
+
+// ContainerLogs copies the stdout and stderr of the container to w.
+func (c Compose) ContainerLogs(ctx context.Context, containerName string, w io.Writer) error {
+	r, err := c.cli.Client().ContainerLogs(ctx, containerName, container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = r.Close() }()
+
+	_, err = stdcopy.StdCopy(w, w, r)
+	return err
+}
+
+type LogsOption func(*api.LogOptions)
+
+// WithFollow keeps streaming the logs, including the ones of the containers
+// started meanwhile.
+func WithFollow() LogsOption {
+	return func(o *api.LogOptions) {
+		o.Follow = true
+	}
+}
+
+// WithSince shows the logs since the timestamp or the relative duration.
+func WithSince(since string) LogsOption {
+	return func(o *api.LogOptions) {
+		o.Since = since
+	}
+}
+
+// WithTail shows the number of lines from the end of the logs, "all" shows
+// every line.
+func WithTail(tail string) LogsOption {
+	return func(o *api.LogOptions) {
+		o.Tail = tail
+	}
+}
+
+// Logs passes the logs of the project containers to the consumer, of all the
+// services if none is specified. The containers of the init jobs are included.
+func (c Compose) Logs(ctx context.Context, prjName string, services []string, consumer api.LogConsumer, opts ...LogsOption) error {
+	options := api.LogOptions{
+		Services: services,
+		Tail:     "all",
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return c.svc.Logs(ctx, prjName, consumer, options)
+}
+
+type redactingConsumer struct {
+	api.LogConsumer
+	replacer *strings.Replacer
+}
+
+// NewRedactingLogConsumer replaces the secret values in the messages passed
+// to the consumer. The lines of a multi-line secret are replaced one by one.
+// Every value is replaced however short it is, so the values which are not
+// secret, e.g. ports, should not be passed.
+func NewRedactingLogConsumer(consumer api.LogConsumer, secrets []string) api.LogConsumer {
+	seen := map[string]bool{}
+	var values []string
+	for _, secret := range secrets {
+		for _, line := range strings.Split(secret, "\n") {
+			line = strings.TrimSpace(line)
+			if len(line) == 0 || seen[line] {
+				continue
+			}
+			seen[line] = true
+			values = append(values, line)
+		}
+	}
+	if len(values) == 0 {
+		return consumer
+	}
+
+	// the longest match wins when a secret contains another one
+	sort.Slice(values, func(i, j int) bool {
+		if len(values[i]) != len(values[j]) {
+			return len(values[i]) > len(values[j])
+		}
+		return values[i] < values[j]
+	})
+
+	pairs := make([]string, 0, 2*len(values))
+	for _, v := range values {
+		pairs = append(pairs, v, redacted)
+	}
+	return &redactingConsumer{LogConsumer: consumer, replacer: strings.NewReplacer(pairs...)}
+}
+
+func (r *redactingConsumer) Log(containerName, message string) {
+	r.LogConsumer.Log(containerName, r.replacer.Replace(message))
+}
+
+func (r *redactingConsumer) Err(containerName, message string) {
+	r.LogConsumer.Err(containerName, r.replacer.Replace(message))
+}
@@ -0,0 +1,68 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package compose
+
+import (
+	"testing"
+)
+
+type recordingConsumer struct {
+	lines []string
+}
+
+func (r *recordingConsumer) Log(_, message string) { r.lines = append(r.lines, message) }
+func (r *recordingConsumer) Err(_, message string) { r.lines = append(r.lines, message) }
+func (r *recordingConsumer) Status(_, _ string)    {}
+func (r *recordingConsumer) Register(_ string)     {}
+
+func TestRedactingLogConsumer(t *testing.T) {
+	secrets := []string{
+		"s3cr3t",
+		"s3cr3t-longer",
+		"5432",
+		"abc",
+		"-----BEGIN KEY-----\nMIIEvQIBADANBg\n-----END KEY-----\n",
+	}
+
+	tests := []struct {
+		name    string
+		message string
+		want    string
+	}{
+		{"Clear", "database is ready", "database is ready"},
+		{"Secret", "password=s3cr3t", "password=" + redacted},
+		{"LongestMatch", "password=s3cr3t-longer;", "password=" + redacted + ";"},
+		{"Twice", "s3cr3t s3cr3t", redacted + " " + redacted},
+		{"Short", "listening on port 5432, abc", "listening on port " + redacted + ", " + redacted},
+		{"MultiLine", "key MIIEvQIBADANBg", "key " + redacted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &recordingConsumer{}
+			NewRedactingLogConsumer(rec, secrets).Log("adpg", tt.message)
+			if len(rec.lines) != 1 || rec.lines[0] != tt.want {
+				t.Errorf("got %q, want %q", rec.lines, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactingLogConsumerNoSecrets(t *testing.T) {
+	rec := &recordingConsumer{}
+	if c := NewRedactingLogConsumer(rec, []string{"", " \n"}); c != rec {
+		t.Errorf("consumer is wrapped without secrets")
+	}
+}
@@ -0,0 +1,283 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package compose
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/docker/compose/v2/pkg/api"
+	"github.com/docker/docker/api/types/container"
+)
+
+// ADFieldsLabelKey holds the hashes of the service fields the container was
+// created with, so a plan can tell which of them have changed. The secrets
+// content is not a part of the compose configuration hash, its hash in the
+// label is the only way to tell that a secret, e.g. a renewed certificate, has
+// changed.
+const ADFieldsLabelKey = ADLabel + "/fields"
+
+type Action string
+
+const (
+	ActionNone     Action = "none"
+	ActionCreate   Action = "create"
+	ActionRecreate Action = "recreate"
+	ActionRemove   Action = "remove"
+)
+
+type ServiceChange struct {
+	Service string   `json:"service"`
+	Action  Action   `json:"action"`
+	Fields  []string `json:"fields,omitempty"`
+	// Force is set when only the secrets have changed. docker compose does not
+	// see such a change, the container has to be recreated explicitly.
+	Force bool `json:"-"`
+}
+
+type Plan struct {
+	Project     string          `json:"project"`
+	ProjectHash string          `json:"project_hash"`
+	Services    []ServiceChange `json:"services"`
+	VaultInit   bool            `json:"vault_init"`
+}
+
+func shortHash(key []byte, v any) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	if len(key) > 0 {
+		h = hmac.New(sha256.New, key)
+	}
+	h.Write(b)
+	return hex.EncodeToString(h.Sum(nil)[:6]), nil
+}
+
+// FieldHashes returns the hashes of the service fields which are reported
+// in a plan. Secrets are hashed with their content, so the key should be set
+// if the content is sensitive.
+func FieldHashes(prj *types.Project, svc types.ServiceConfig, key []byte) (map[string]string, error) {
+	secrets := make(map[string]string, len(svc.Secrets))
+	for _, sec := range svc.Secrets {
+		secrets[sec.Target] = sec.Source + ":" + prj.Secrets[sec.Source].Content
+	}
+
+	fields := map[string]any{
+		"image":       svc.Image,
+		"command":     svc.Command,
+		"entrypoint":  svc.Entrypoint,
+		"environment": svc.Environment,
+		"ports":       svc.Ports,
+		"volumes":     svc.Volumes,
+		"secrets":     secrets,
+		"user":        svc.User,
+		"healthcheck": svc.HealthCheck,
+	}
+
+	hashes := make(map[string]string, len(fields))
+	for k, v := range fields {
+		h, err := shortHash(key, v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", k, err)
+		}
+		hashes[k] = h
+	}
+
+	return hashes, nil
+}
+
+func encodeFields(hashes map[string]string) string {
+	keys := make([]string, 0, len(hashes))
+	for k := range hashes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k + "=" + hashes[k]
+	}
+	return strings.Join(pairs, ",")
+}
+
+func decodeFields(s string) map[string]string {
+	hashes := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		if k, v, ok := strings.Cut(pair, "="); ok {
+			hashes[k] = v
+		}
+	}
+	return hashes
+}
+
+// SetFieldsLabel stores the field hashes of every service in a custom label.
+// Custom labels are not a part of the compose configuration hash.
+func SetFieldsLabel(prj *types.Project, key []byte) error {
+	for name, svc := range prj.Services {
+		hashes, err := FieldHashes(prj, svc, key)
+		if err != nil {
+			return fmt.Errorf("service %s: %v", name, err)
+		}
+		svc.CustomLabels = svc.CustomLabels.Add(ADFieldsLabelKey, encodeFields(hashes))
+		prj.Services[name] = svc
+	}
+	return nil
+}
+
+// NewPlan compares the project services with the project containers. The
+// action of a service is the one docker compose is going to take: containers
+// are recreated when the configuration hash differs. Containers of services
+// missing in the project are listed as orphans, they are removed only when the
+// plan is applied with the orphans removal. The project services are
+// expected to be labeled by SetFieldsLabel.
+func NewPlan(prj *types.Project, containers []container.Summary, key []byte) (*Plan, error) {
+	plan := &Plan{Project: prj.Name}
+
+	byService := map[string]container.Summary{}
+	for _, c := range containers {
+		if _, job := c.Labels[ADJobLabelKey]; job || c.Labels[api.OneoffLabel] == "True" {
+			continue
+		}
+		byService[c.Labels[api.ServiceLabel]] = c
+	}
+
+	projectHash := sha256.New()
+	for _, name := range prj.ServiceNames() {
+		svc := prj.Services[name]
+
+		configHash, err := ServiceHash(svc)
+		if err != nil {
+			return nil, err
+		}
+		fields, err := FieldHashes(prj, svc, key)
+		if err != nil {
+			return nil, err
+		}
+		_, _ = fmt.Fprintf(projectHash, "%s\x00%s\x00%s\x00", name, configHash, encodeFields(fields))
+
+		change := ServiceChange{Service: name, Action: ActionNone}
+		c, ok := byService[name]
+		switch {
+		case !ok:
+			change.Action = ActionCreate
+		case c.Labels[api.ConfigHashLabel] != configHash:
+			change.Action = ActionRecreate
+			change.Fields = changedFields(fields, c.Labels[ADFieldsLabelKey])
+		case secretsChanged(fields, c.Labels[ADFieldsLabelKey]):
+			change.Action = ActionRecreate
+			change.Fields = []string{"secrets"}
+			change.Force = true
+		}
+
+		plan.Services = append(plan.Services, change)
+		delete(byService, name)
+	}
+
+	orphans := make([]string, 0, len(byService))
+	for name := range byService {
+		orphans = append(orphans, name)
+	}
+	sort.Strings(orphans)
+	for _, name := range orphans {
+		plan.Services = append(plan.Services, ServiceChange{Service: name, Action: ActionRemove})
+	}
+
+	plan.ProjectHash = hex.EncodeToString(projectHash.Sum(nil))
+	return plan, nil
+}
+
+// changedFields compares the desired field hashes with the container label.
+// Containers created without the label can't tell the fields, "unknown" is
+// reported for them.
+func changedFields(want map[string]string, label string) []string {
+	if len(label) == 0 {
+		return []string{"unknown"}
+	}
+
+	got := decodeFields(label)
+	var fields []string
+	for k, v := range want {
+		if got[k] != v {
+			fields = append(fields, k)
+		}
+	}
+	if len(fields) == 0 {
+		fields = append(fields, "other")
+	}
+
+	sort.Strings(fields)
+	return fields
+}
+
+// secretsChanged compares the desired secrets hash with the container label.
+// Containers created without the label are left as they are.
+func secretsChanged(want map[string]string, label string) bool {
+	if len(label) == 0 {
+		return false
+	}
+	return decodeFields(label)["secrets"] != want["secrets"]
+}
+
+// Forced returns the services which have to be recreated explicitly.
+func (p *Plan) Forced() []string {
+	var out []string
+	for _, s := range p.Services {
+		if s.Force {
+			out = append(out, s.Service)
+		}
+	}
+	return out
+}
+
+func (p *Plan) Count(action Action) int {
+	var n int
+	for _, s := range p.Services {
+		if s.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+func (p *Plan) HasChanges() bool {
+	return p.VaultInit || len(p.Services) != p.Count(ActionNone)
+}
+
+// Equal reports whether both plans describe the same project and actions.
+func (p *Plan) Equal(o *Plan) bool {
+	if p.Project != o.Project || p.ProjectHash != o.ProjectHash || p.VaultInit != o.VaultInit ||
+		len(p.Services) != len(o.Services) {
+		return false
+	}
+
+	for i, s := range p.Services {
+		t := o.Services[i]
+		if s.Service != t.Service || s.Action != t.Action || strings.Join(s.Fields, ",") != strings.Join(t.Fields, ",") {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,166 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package compose
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/docker/compose/v2/pkg/api"
+	"github.com/docker/docker/api/types/container"
+)
+
+var planKey = []byte("key")
+
+func planProject(image string, secret string) *types.Project {
+	return &types.Project{
+		Name: "demo",
+		Services: types.Services{
+			"adcm": {Name: "adcm", Image: image,
+				Secrets: []types.ServiceSecretConfig{{Source: "adcm-password", Target: "/run/secrets/password"}}},
+			"adpg": {Name: "adpg", Image: "postgres:16"},
+		},
+		Secrets: types.Secrets{"adcm-password": {Name: "adcm-password", Content: secret}},
+	}
+}
+
+// runningContainers returns the containers as if the project has been applied.
+func runningContainers(t *testing.T, prj *types.Project, services ...string) []container.Summary {
+	t.Helper()
+
+	if err := SetFieldsLabel(prj, planKey); err != nil {
+		t.Fatal(err)
+	}
+
+	var out []container.Summary
+	for _, name := range services {
+		svc := prj.Services[name]
+		hash, err := ServiceHash(svc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, container.Summary{Labels: map[string]string{
+			api.ServiceLabel:    name,
+			api.ConfigHashLabel: hash,
+			ADFieldsLabelKey:    svc.CustomLabels[ADFieldsLabelKey],
+		}})
+	}
+	return out
+}
+
+// withoutFieldsLabel drops the fields label as if the containers were created
+// by a version without it.
+func withoutFieldsLabel(containers []container.Summary) []container.Summary {
+	for _, c := range containers {
+		delete(c.Labels, ADFieldsLabelKey)
+	}
+	return containers
+}
+
+func TestNewPlan(t *testing.T) {
+	orphan := container.Summary{Labels: map[string]string{api.ServiceLabel: "consul"}}
+	noLabel := container.Summary{Labels: map[string]string{api.ServiceLabel: "adcm", api.ConfigHashLabel: "old"}}
+
+	tests := []struct {
+		name       string
+		prj        *types.Project
+		containers []container.Summary
+		want       []ServiceChange
+	}{
+		{"Create", planProject("adcm:1", "s"), nil, []ServiceChange{
+			{Service: "adcm", Action: ActionCreate},
+			{Service: "adpg", Action: ActionCreate},
+		}},
+		{"Unchanged", planProject("adcm:1", "s"), runningContainers(t, planProject("adcm:1", "s"), "adcm", "adpg"), []ServiceChange{
+			{Service: "adcm", Action: ActionNone},
+			{Service: "adpg", Action: ActionNone},
+		}},
+		{"Recreate", planProject("adcm:2", "s"), runningContainers(t, planProject("adcm:1", "s"), "adcm", "adpg"), []ServiceChange{
+			{Service: "adcm", Action: ActionRecreate, Fields: []string{"image"}},
+			{Service: "adpg", Action: ActionNone},
+		}},
+		{"RecreateSecret", planProject("adcm:1", "t"), runningContainers(t, planProject("adcm:1", "s"), "adcm", "adpg"), []ServiceChange{
+			{Service: "adcm", Action: ActionRecreate, Fields: []string{"secrets"}, Force: true},
+			{Service: "adpg", Action: ActionNone},
+		}},
+		{"RecreateImageAndSecret", planProject("adcm:2", "t"), runningContainers(t, planProject("adcm:1", "s"), "adcm", "adpg"), []ServiceChange{
+			{Service: "adcm", Action: ActionRecreate, Fields: []string{"image", "secrets"}},
+			{Service: "adpg", Action: ActionNone},
+		}},
+		{"UnchangedWithoutLabel", planProject("adcm:1", "t"), withoutFieldsLabel(runningContainers(t, planProject("adcm:1", "s"), "adcm", "adpg")), []ServiceChange{
+			{Service: "adcm", Action: ActionNone},
+			{Service: "adpg", Action: ActionNone},
+		}},
+		{"RecreateWithoutLabel", planProject("adcm:2", "s"), []container.Summary{noLabel}, []ServiceChange{
+			{Service: "adcm", Action: ActionRecreate, Fields: []string{"unknown"}},
+			{Service: "adpg", Action: ActionCreate},
+		}},
+		{"Orphan", planProject("adcm:1", "s"), append(runningContainers(t, planProject("adcm:1", "s"), "adcm", "adpg"), orphan), []ServiceChange{
+			{Service: "adcm", Action: ActionNone},
+			{Service: "adpg", Action: ActionNone},
+			{Service: "consul", Action: ActionRemove},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := SetFieldsLabel(tt.prj, planKey); err != nil {
+				t.Fatal(err)
+			}
+			got, err := NewPlan(tt.prj, tt.containers, planKey)
+			if err != nil {
+				t.Fatalf("NewPlan() error = %v", err)
+			}
+			if !reflect.DeepEqual(got.Services, tt.want) {
+				t.Errorf("NewPlan() = %v, want %v", got.Services, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanForced(t *testing.T) {
+	prj := planProject("adcm:1", "t")
+	containers := runningContainers(t, planProject("adcm:1", "s"), "adcm", "adpg")
+	if err := SetFieldsLabel(prj, planKey); err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := NewPlan(prj, containers, planKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := plan.Forced(), []string{"adcm"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Forced() = %v, want %v", got, want)
+	}
+}
+
+func TestPlanProjectHash(t *testing.T) {
+	plan := func(secret string) *Plan {
+		p, err := NewPlan(planProject("adcm:1", secret), nil, planKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	if !plan("s").Equal(plan("s")) {
+		t.Errorf("Equal() = false for the same project")
+	}
+	// the plan must not be replayed against another secret value
+	if plan("s").Equal(plan("t")) {
+		t.Errorf("Equal() = true for different secrets")
+	}
+}
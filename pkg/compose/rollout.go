@@ -0,0 +1,87 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package compose
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/sirupsen/logrus"
+)
+
+// RolloutError reports the services of a halted rollout.
+type RolloutError struct {
+	Failed  []string
+	Updated []string
+	Pending []string
+	Err     error
+}
+
+func (e *RolloutError) Error() string {
+	msg := fmt.Sprintf("rollout halted, %s failed: %v", strings.Join(e.Failed, ", "), e.Err)
+	if len(e.Updated) > 0 {
+		msg += fmt.Sprintf("; updated: %s", strings.Join(e.Updated, ", "))
+	}
+	if len(e.Pending) > 0 {
+		msg += fmt.Sprintf("; not updated: %s", strings.Join(e.Pending, ", "))
+	}
+	return msg
+}
+
+func (e *RolloutError) Unwrap() error {
+	return e.Err
+}
+
+// Rollout recreates the services in batches of maxUnavailable. A batch is
+// started only when the previous one is healthy, or running if the services
+// have no health check. The rollout halts on the first failed batch and the
+// remaining services are left as they are.
+func (c Compose) Rollout(ctx context.Context, prj *types.Project, services []string, maxUnavailable int, opts ...UpOption) error {
+	var done int
+	for _, batch := range batches(services, maxUnavailable) {
+		logrus.Infof("Restarting %s ...", strings.Join(batch, ", "))
+
+		svcPrj, err := prj.WithSelectedServices(batch)
+		if err != nil {
+			return err
+		}
+		if err = c.Up(ctx, svcPrj, true, opts...); err != nil {
+			return &RolloutError{
+				Failed:  batch,
+				Updated: services[:done],
+				Pending: services[done+len(batch):],
+				Err:     err,
+			}
+		}
+		done += len(batch)
+	}
+
+	return nil
+}
+
+func batches(services []string, size int) [][]string {
+	if size < 1 {
+		size = 1
+	}
+
+	var out [][]string
+	for i := 0; i < len(services); i += size {
+		out = append(out, services[i:min(i+size, len(services))])
+	}
+	return out
+}
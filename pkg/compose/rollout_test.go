@@ -0,0 +1,43 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package compose
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBatches(t *testing.T) {
+	services := []string{"adcm-1", "adcm-2", "adcm-3"}
+
+	tests := []struct {
+		name string
+		size int
+		want [][]string
+	}{
+		{"OneByOne", 1, [][]string{{"adcm-1"}, {"adcm-2"}, {"adcm-3"}}},
+		{"Zero", 0, [][]string{{"adcm-1"}, {"adcm-2"}, {"adcm-3"}}},
+		{"Two", 2, [][]string{{"adcm-1", "adcm-2"}, {"adcm-3"}}},
+		{"All", 5, [][]string{{"adcm-1", "adcm-2", "adcm-3"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := batches(services, tt.size); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("batches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package compose
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"path"
+	"time"
+
+	"github.com/containerd/errdefs"
+	"github.com/docker/compose/v2/pkg/api"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/volume"
+)
+
+const volumeMountPath = "/volume"
+
+// Volume describes a named volume or a bind mounted host directory.
+type Volume struct {
+	Type   string
+	Source string
+}
+
+func (v Volume) mount() mount.Mount {
+	return mount.Mount{
+		Type:   mount.Type(v.Type),
+		Source: v.Source,
+		Target: volumeMountPath,
+	}
+}
+
+func (c Compose) VolumeExists(ctx context.Context, name string) (bool, error) {
+	_, err := c.cli.Client().VolumeInspect(ctx, name)
+	if errdefs.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (c Compose) VolumeRemove(ctx context.Context, name string) error {
+	return c.cli.Client().VolumeRemove(ctx, name, false)
+}
+
+// VolumeCreate creates a named volume labeled as a volume of the compose
+// project, so docker compose treats it as its own.
+func (c Compose) VolumeCreate(ctx context.Context, prjName, key, name string) error {
+	_, err := c.cli.Client().VolumeCreate(ctx, volume.CreateOptions{
+		Name: name,
+		Labels: map[string]string{
+			api.ProjectLabel: prjName,
+			api.VolumeLabel:  key,
+			api.VersionLabel: api.ComposeVersion,
+		},
+	})
+	return err
+}
+
+// helperContainer creates (but does not start) a container with the volume
+// mounted. The docker archive API works on stopped containers, so the image
+// only has to exist locally.
+func (c Compose) helperContainer(ctx context.Context, image string, v Volume, readOnly bool) (string, func(), error) {
+	mnt := v.mount()
+	mnt.ReadOnly = readOnly
+
+	resp, err := c.cli.Client().ContainerCreate(ctx,
+		&container.Config{Image: image, Labels: map[string]string{ADLabel: ""}},
+		&container.HostConfig{Mounts: []mount.Mount{mnt}},
+		nil, nil, "")
+	if err != nil {
+		return "", nil, err
+	}
+
+	remove := func() {
+		_ = c.cli.Client().ContainerRemove(context.WithoutCancel(ctx), resp.ID,
+			container.RemoveOptions{Force: true})
+	}
+
+	return resp.ID, remove, nil
+}
+
+// VolumeExport writes the volume content to w as a tar archive.
+func (c Compose) VolumeExport(ctx context.Context, image string, v Volume, w io.Writer) error {
+	id, remove, err := c.helperContainer(ctx, image, v, true)
+	if err != nil {
+		return err
+	}
+	defer remove()
+
+	r, _, err := c.cli.Client().CopyFromContainer(ctx, id, volumeMountPath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = r.Close() }()
+
+	_, err = io.Copy(w, r)
+	return err
+}
+
+// VolumeImport extracts the tar archive created by VolumeExport into the
+// volume. Files ownership is preserved.
+func (c Compose) VolumeImport(ctx context.Context, image string, v Volume, r io.Reader) error {
+	id, remove, err := c.helperContainer(ctx, image, v, false)
+	if err != nil {
+		return err
+	}
+	defer remove()
+
+	return c.cli.Client().CopyToContainer(ctx, id, "/", r, container.CopyToContainerOptions{})
+}
+
+// VolumeReadFile returns the content of the file in the volume. The error
+// is a not found one if the file does not exist.
+func (c Compose) VolumeReadFile(ctx context.Context, image string, v Volume, name string) ([]byte, error) {
+	id, remove, err := c.helperContainer(ctx, image, v, true)
+	if err != nil {
+		return nil, err
+	}
+	defer remove()
+
+	r, _, err := c.cli.Client().CopyFromContainer(ctx, id, path.Join(volumeMountPath, name))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = r.Close() }()
+
+	tr := tar.NewReader(r)
+	if _, err = tr.Next(); err != nil {
+		return nil, err
+	}
+	return io.ReadAll(tr)
+}
+
+// VolumeWriteFile writes the file to the volume, the file is owned by root.
+func (c Compose) VolumeWriteFile(ctx context.Context, image string, v Volume, name string, data []byte) error {
+	id, remove, err := c.helperContainer(ctx, image, v, false)
+	if err != nil {
+		return err
+	}
+	defer remove()
+
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0o644,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}
+	if err = tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if _, err = tw.Write(data); err != nil {
+		return err
+	}
+	if err = tw.Close(); err != nil {
+		return err
+	}
+
+	return c.cli.Client().CopyToContainer(ctx, id, volumeMountPath, buf, container.CopyToContainerOptions{})
+}
+
+// ImageDigest returns the repo digest of a local image, if any.
+func (c Compose) ImageDigest(ctx context.Context, image string) (string, error) {
+	inspect, err := c.cli.Client().ImageInspect(ctx, image)
+	if err != nil {
+		return "", err
+	}
+	if len(inspect.RepoDigests) > 0 {
+		return inspect.RepoDigests[0], nil
+	}
+	return inspect.ID, nil
+}
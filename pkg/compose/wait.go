@@ -0,0 +1,195 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package compose
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/docker/compose/v2/pkg/api"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+)
+
+// ServiceState is the state of the service container a wait has ended with.
+type ServiceState struct {
+	Container string
+	ExitCode  int
+}
+
+// waitCondition reports whether the container has reached the state waited
+// for. An error ends the wait.
+type waitCondition func(container.InspectResponse) (bool, error)
+
+func running(c container.InspectResponse) (bool, error) {
+	return c.State.Running && !c.State.Restarting, nil
+}
+
+// healthy is running for the containers without a health check. A container
+// which has exited and is not restarted fails the wait.
+func healthy(c container.InspectResponse) (bool, error) {
+	if exited(c) && (c.HostConfig == nil || c.HostConfig.RestartPolicy.IsNone()) {
+		return false, fmt.Errorf("container %s exited with code %d", containerName(c), c.State.ExitCode)
+	}
+	if ok, _ := running(c); !ok {
+		return false, nil
+	}
+	return c.State.Health == nil || c.State.Health.Status == container.Healthy, nil
+}
+
+func exited(c container.InspectResponse) bool {
+	return c.State.Status == container.StateExited || c.State.Status == container.StateDead
+}
+
+func exitedCondition(c container.InspectResponse) (bool, error) {
+	return exited(c), nil
+}
+
+func containerName(c container.InspectResponse) string {
+	return strings.TrimPrefix(c.Name, "/")
+}
+
+// WaitForRunning waits until the containers of the services are running. A
+// zero timeout waits until ctx is done.
+func (c Compose) WaitForRunning(ctx context.Context, prj *types.Project, services []string, timeout time.Duration) (map[string]ServiceState, error) {
+	return c.wait(ctx, prj, services, timeout, "running", running)
+}
+
+// WaitForHealthy waits until the containers of the services are healthy, or
+// running if they have no health check. A zero timeout waits until ctx is
+// done.
+func (c Compose) WaitForHealthy(ctx context.Context, prj *types.Project, services []string, timeout time.Duration) (map[string]ServiceState, error) {
+	return c.wait(ctx, prj, services, timeout, "healthy", healthy)
+}
+
+// WaitForExit waits until the containers of the services have exited, their
+// exit codes are returned. A zero timeout waits until ctx is done.
+func (c Compose) WaitForExit(ctx context.Context, prj *types.Project, services []string, timeout time.Duration) (map[string]ServiceState, error) {
+	return c.wait(ctx, prj, services, timeout, "exited", exitedCondition)
+}
+
+// wait checks the service containers on every event of the project
+// containers. Only the containers created from the current configuration of
+// the services are taken into account, the ones about to be recreated are
+// not.
+func (c Compose) wait(ctx context.Context, prj *types.Project, services []string, timeout time.Duration,
+	state string, cond waitCondition) (map[string]ServiceState, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	hashes := make(map[string]string, len(services))
+	for _, name := range services {
+		svc, ok := prj.Services[name]
+		if !ok {
+			return nil, fmt.Errorf("service %s not found in project %s", name, prj.Name)
+		}
+		hash, err := ServiceHash(svc)
+		if err != nil {
+			return nil, err
+		}
+		hashes[name] = hash
+	}
+
+	out := make(map[string]ServiceState, len(services))
+
+	// check reports whether the container has ended the wait of its service
+	check := func(id string) error {
+		inspect, err := c.Inspect(ctx, id)
+		if err != nil {
+			// removed meanwhile
+			return nil
+		}
+		service := inspect.Config.Labels[api.ServiceLabel]
+		if _, ok := out[service]; ok || hashes[service] != inspect.Config.Labels[api.ConfigHashLabel] {
+			return nil
+		}
+
+		ok, err := cond(inspect)
+		if err != nil {
+			return fmt.Errorf("%s: %v", service, err)
+		}
+		if ok {
+			out[service] = ServiceState{Container: containerName(inspect), ExitCode: inspect.State.ExitCode}
+		}
+		return nil
+	}
+
+	// subscribe before listing, so no state change is missed
+	evCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	evs, errs := c.Events(evCtx, prj.Name)
+
+	containers, err := c.list(ctx, true,
+		filters.Arg("label", api.ProjectLabel+"="+prj.Name),
+		filters.Arg("label", ADLabel),
+	)
+	if err != nil {
+		return nil, err
+	}
+	for _, ctr := range containers {
+		if err = check(ctr.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	for len(out) < len(hashes) {
+		select {
+		case <-ctx.Done():
+			if timeout > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return out, fmt.Errorf("timed out after %s waiting for %s to be %s",
+					timeout, strings.Join(pending(hashes, out), ", "), state)
+			}
+			return out, ctx.Err()
+		case err = <-errs:
+			if ctx.Err() == nil {
+				return out, fmt.Errorf("container events: %v", err)
+			}
+		case ev, ok := <-evs:
+			if !ok {
+				// the error or ctx ends the wait
+				evs = nil
+				continue
+			}
+			if _, wanted := hashes[ev.Service]; !wanted {
+				continue
+			}
+			if err = check(ev.ID); err != nil {
+				return out, err
+			}
+		}
+	}
+
+	return out, nil
+}
+
+func pending(hashes map[string]string, done map[string]ServiceState) []string {
+	var names []string
+	for name := range hashes {
+		if _, ok := done[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,78 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package compose
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+func inspectState(status container.ContainerState, health container.HealthStatus, restart container.RestartPolicyMode) container.InspectResponse {
+	state := &container.State{Status: status, Running: status == container.StateRunning}
+	if len(health) > 0 {
+		state.Health = &container.Health{Status: health}
+	}
+	return container.InspectResponse{
+		ContainerJSONBase: &container.ContainerJSONBase{
+			Name:       "/p-vault",
+			State:      state,
+			HostConfig: &container.HostConfig{RestartPolicy: container.RestartPolicy{Name: restart}},
+		},
+	}
+}
+
+func TestWaitConditions(t *testing.T) {
+	tests := []struct {
+		name      string
+		inspect   container.InspectResponse
+		cond      waitCondition
+		want      bool
+		wantError bool
+	}{
+		{"Running", inspectState(container.StateRunning, "", ""), running, true, false},
+		{"Created", inspectState(container.StateCreated, "", ""), running, false, false},
+		{"HealthyNoCheck", inspectState(container.StateRunning, "", ""), healthy, true, false},
+		{"HealthyStarting", inspectState(container.StateRunning, container.Starting, ""), healthy, false, false},
+		{"Healthy", inspectState(container.StateRunning, container.Healthy, ""), healthy, true, false},
+		{"HealthyExited", inspectState(container.StateExited, "", container.RestartPolicyDisabled), healthy, false, true},
+		{"HealthyRestarted", inspectState(container.StateExited, "", container.RestartPolicyAlways), healthy, false, false},
+		{"Exited", inspectState(container.StateExited, "", ""), exitedCondition, true, false},
+		{"NotExited", inspectState(container.StateRunning, "", ""), exitedCondition, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cond(tt.inspect)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("error = %v, wantError %v", err, tt.wantError)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPending(t *testing.T) {
+	hashes := map[string]string{"vault-1": "a", "vault-2": "b", "vault-3": "c"}
+	done := map[string]ServiceState{"vault-2": {Container: "p-vault-2"}}
+
+	want := []string{"vault-1", "vault-3"}
+	if got := pending(hashes, done); !reflect.DeepEqual(got, want) {
+		t.Errorf("pending() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,454 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package images
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/arenadata/adcm-installer/pkg/registry-client"
+
+	"github.com/distribution/reference"
+	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+const (
+	// BundleFileName lists the images of the bundle, the rest of the bundle
+	// is an OCI image layout loadable by docker load.
+	BundleFileName = "bundle.json"
+	BundleVersion  = 1
+
+	ociLayout        = `{"imageLayoutVersion": "1.0.0"}`
+	manifestFileName = "manifest.json"
+
+	dockerHost           = "docker.io"
+	dockerRegistryDomain = "registry-1.docker.io"
+
+	mediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeDockerManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+)
+
+var manifestMediaTypes = strings.Join([]string{
+	ocispec.MediaTypeImageIndex,
+	ocispec.MediaTypeImageManifest,
+	mediaTypeDockerManifestList,
+	mediaTypeDockerManifest,
+}, ", ")
+
+type Image struct {
+	Ref            string        `json:"ref"`
+	ManifestDigest digest.Digest `json:"manifest_digest"`
+	ConfigDigest   digest.Digest `json:"config_digest"`
+	Platform       string        `json:"platform"`
+}
+
+type bundleFile struct {
+	Version int     `json:"version"`
+	Images  []Image `json:"images"`
+}
+
+// manifestItem is an entry of the docker load manifest.json
+type manifestItem struct {
+	Config   string
+	RepoTags []string
+	Layers   []string
+}
+
+type Bundle struct {
+	Images []Image
+
+	dir      string
+	index    ocispec.Index
+	manifest []manifestItem
+}
+
+func NewBundle() (*Bundle, error) {
+	dir, err := os.MkdirTemp("", "adi-images-*")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Bundle{
+		dir: dir,
+		index: ocispec.Index{
+			Versioned: specs.Versioned{SchemaVersion: 2},
+			MediaType: ocispec.MediaTypeImageIndex,
+		},
+	}, nil
+}
+
+func (b *Bundle) blobPath(d digest.Digest) string {
+	return filepath.Join(b.dir, ocispec.ImageBlobsDir, d.Algorithm().String(), d.Encoded())
+}
+
+func blobName(d digest.Digest) string {
+	return path.Join(ocispec.ImageBlobsDir, d.Algorithm().String(), d.Encoded())
+}
+
+// writeBlob stores the blob verifying its digest. Blobs shared by several
+// images are stored once.
+func (b *Bundle) writeBlob(d digest.Digest, r io.Reader) error {
+	p := b.blobPath(d)
+	if _, err := os.Stat(p); err == nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".blob-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	verifier := d.Verifier()
+	if _, err = io.Copy(io.MultiWriter(tmp, verifier), r); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if !verifier.Verified() {
+		return fmt.Errorf("blob %s: digest mismatch", d)
+	}
+
+	return os.Rename(tmp.Name(), p)
+}
+
+type manifestOrIndex struct {
+	MediaType string               `json:"mediaType"`
+	Config    ocispec.Descriptor   `json:"config"`
+	Layers    []ocispec.Descriptor `json:"layers"`
+	Manifests []ocispec.Descriptor `json:"manifests"`
+}
+
+func registryDomain(ref reference.Named) string {
+	domain := reference.Domain(ref)
+	if domain == dockerHost {
+		domain = dockerRegistryDomain
+	}
+	return domain
+}
+
+// Add downloads the image for the platform from its registry.
+func (b *Bundle) Add(ctx context.Context, image string, platform ocispec.Platform, opts ...client.RegistryOption) error {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return err
+	}
+	named = reference.TagNameOnly(named)
+
+	repo := reference.Path(named)
+	tag := named.(reference.Tagged).Tag()
+	reg := client.NewRegistryClient(registryDomain(named), opts...)
+
+	data, err := reg.Manifest(repo, tag, manifestMediaTypes)
+	if err != nil {
+		return err
+	}
+
+	var m manifestOrIndex
+	if err = json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+
+	desc := ocispec.Descriptor{
+		MediaType: m.MediaType,
+		Digest:    digest.FromBytes(data),
+		Size:      int64(len(data)),
+		Platform:  &platform,
+	}
+
+	if len(m.Manifests) > 0 {
+		var found bool
+		for _, item := range m.Manifests {
+			if item.Platform != nil && item.Platform.OS == platform.OS &&
+				item.Platform.Architecture == platform.Architecture &&
+				(len(platform.Variant) == 0 || item.Platform.Variant == platform.Variant) {
+				desc, found = item, true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: no image for platform %s/%s", image, platform.OS, platform.Architecture)
+		}
+
+		if data, err = reg.Manifest(repo, desc.Digest.String(), desc.MediaType); err != nil {
+			return err
+		}
+		m = manifestOrIndex{}
+		if err = json.Unmarshal(data, &m); err != nil {
+			return err
+		}
+	}
+
+	if err = b.writeBlob(desc.Digest, bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("%s: manifest: %v", image, err)
+	}
+
+	config, err := reg.Blob(repo, m.Config.Digest.String(), m.Config.MediaType)
+	if err != nil {
+		return err
+	}
+	if err = b.writeBlob(m.Config.Digest, bytes.NewReader(config)); err != nil {
+		return fmt.Errorf("%s: config: %v", image, err)
+	}
+
+	familiar := reference.FamiliarString(named)
+	item := manifestItem{
+		Config:   blobName(m.Config.Digest),
+		RepoTags: []string{familiar},
+	}
+
+	for _, layer := range m.Layers {
+		if err = b.addLayer(ctx, reg, repo, layer.Digest); err != nil {
+			return fmt.Errorf("%s: layer %s: %v", image, layer.Digest, err)
+		}
+		item.Layers = append(item.Layers, blobName(layer.Digest))
+	}
+
+	desc.Annotations = map[string]string{
+		"io.containerd.image.name": named.String(),
+		ocispec.AnnotationRefName:  tag,
+	}
+	b.index.Manifests = append(b.index.Manifests, desc)
+	b.manifest = append(b.manifest, item)
+	b.Images = append(b.Images, Image{
+		Ref:            familiar,
+		ManifestDigest: desc.Digest,
+		ConfigDigest:   m.Config.Digest,
+		Platform:       platform.OS + "/" + platform.Architecture,
+	})
+
+	return nil
+}
+
+func (b *Bundle) addLayer(ctx context.Context, reg *client.RegistryClient, repo string, d digest.Digest) error {
+	if _, err := os.Stat(b.blobPath(d)); err == nil {
+		return nil
+	}
+
+	r, err := reg.BlobReader(ctx, repo, d.String())
+	if err != nil {
+		return err
+	}
+	defer func() { _ = r.Close() }()
+
+	return b.writeBlob(d, r)
+}
+
+func writeJson(tw *tar.Writer, name string, v any) error {
+	data, ok := v.([]byte)
+	if !ok {
+		var err error
+		if data, err = json.Marshal(v); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data))}); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// writeLayout writes the bundle as a tar stream: the bundle file first, then
+// the OCI image layout with the docker load manifest.
+func (b *Bundle) writeLayout(w io.Writer) error {
+	tw := tar.NewWriter(w)
+
+	if err := writeJson(tw, BundleFileName, bundleFile{Version: BundleVersion, Images: b.Images}); err != nil {
+		return err
+	}
+	if err := writeJson(tw, ocispec.ImageLayoutFile, []byte(ociLayout)); err != nil {
+		return err
+	}
+	if err := writeJson(tw, ocispec.ImageIndexFile, b.index); err != nil {
+		return err
+	}
+	if err := writeJson(tw, manifestFileName, b.manifest); err != nil {
+		return err
+	}
+
+	blobsDir := filepath.Join(b.dir, ocispec.ImageBlobsDir)
+	var blobs []string
+	err := filepath.WalkDir(blobsDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+			return err
+		}
+		rel, err := filepath.Rel(b.dir, p)
+		if err != nil {
+			return err
+		}
+		blobs = append(blobs, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	sort.Strings(blobs)
+
+	for _, name := range blobs {
+		if err = writeTarFile(tw, name, filepath.Join(b.dir, filepath.FromSlash(name))); err != nil {
+			return err
+		}
+	}
+
+	return tw.Close()
+}
+
+func writeTarFile(tw *tar.Writer, name, src string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	st, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	if err = tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: st.Size()}); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// Write writes the gzipped bundle.
+func (b *Bundle) Write(w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	if err := b.writeLayout(gz); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Archive returns the uncompressed bundle in the format of docker load.
+func (b *Bundle) Archive() io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(b.writeLayout(pw))
+	}()
+	return pr
+}
+
+// Read extracts the gzipped bundle and verifies the digests of all blobs.
+func Read(r io.Reader) (_ *Bundle, err error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = gz.Close() }()
+
+	b, err := NewBundle()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = b.Close()
+		}
+	}()
+
+	tr := tar.NewReader(gz)
+	hdr, err := tr.Next()
+	if err != nil {
+		return nil, err
+	}
+	if hdr.Name != BundleFileName {
+		return nil, errors.New("not an image bundle: bundle.json not found")
+	}
+
+	var bf bundleFile
+	if err = json.NewDecoder(tr).Decode(&bf); err != nil {
+		return nil, fmt.Errorf("%s: %v", BundleFileName, err)
+	}
+	if bf.Version != BundleVersion {
+		return nil, fmt.Errorf("unsupported bundle version %d", bf.Version)
+	}
+	b.Images = bf.Images
+
+	for {
+		hdr, err = tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch hdr.Name {
+		case ocispec.ImageLayoutFile:
+		case ocispec.ImageIndexFile:
+			err = json.NewDecoder(tr).Decode(&b.index)
+		case manifestFileName:
+			err = json.NewDecoder(tr).Decode(&b.manifest)
+		default:
+			err = b.readBlob(hdr.Name, tr)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", hdr.Name, err)
+		}
+	}
+
+	for _, img := range b.Images {
+		for _, d := range []digest.Digest{img.ManifestDigest, img.ConfigDigest} {
+			if _, err = os.Stat(b.blobPath(d)); err != nil {
+				return nil, fmt.Errorf("%s: blob %s is missing", img.Ref, d)
+			}
+		}
+	}
+
+	return b, nil
+}
+
+func (b *Bundle) readBlob(name string, r io.Reader) error {
+	algo, encoded, ok := strings.Cut(strings.TrimPrefix(name, ocispec.ImageBlobsDir+"/"), "/")
+	if !ok || !strings.HasPrefix(name, ocispec.ImageBlobsDir+"/") {
+		return errors.New("unexpected file")
+	}
+
+	d := digest.NewDigestFromEncoded(digest.Algorithm(algo), encoded)
+	if err := d.Validate(); err != nil {
+		return err
+	}
+
+	return b.writeBlob(d, r)
+}
+
+func (b *Bundle) Close() error {
+	return os.RemoveAll(b.dir)
+}
@@ -0,0 +1,89 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package images
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+)
+
+func testBundle(t *testing.T, config, manifest string) *bytes.Buffer {
+	t.Helper()
+
+	b, err := NewBundle()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = b.Close() }()
+
+	img := Image{Ref: "busybox:1", ManifestDigest: digest.FromString(manifest), ConfigDigest: digest.FromString(config)}
+	for d, data := range map[digest.Digest]string{img.ManifestDigest: manifest, img.ConfigDigest: config} {
+		if err = b.writeBlob(d, strings.NewReader(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b.Images = append(b.Images, img)
+
+	buf := new(bytes.Buffer)
+	if err = b.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+func TestBundleRead(t *testing.T) {
+	buf := testBundle(t, "config", "manifest")
+
+	b, err := Read(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	defer func() { _ = b.Close() }()
+
+	want := []Image{{Ref: "busybox:1", ManifestDigest: digest.FromString("manifest"), ConfigDigest: digest.FromString("config")}}
+	if !reflect.DeepEqual(b.Images, want) {
+		t.Errorf("Read() = %v, want %v", b.Images, want)
+	}
+}
+
+func TestWriteBlob(t *testing.T) {
+	b, err := NewBundle()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = b.Close() }()
+
+	tests := []struct {
+		name    string
+		digest  digest.Digest
+		data    string
+		wantErr bool
+	}{
+		{"Valid", digest.FromString("layer"), "layer", false},
+		{"Corrupted", digest.FromString("layer2"), "corrupted", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := b.writeBlob(tt.digest, strings.NewReader(tt.data)); (err != nil) != tt.wantErr {
+				t.Errorf("writeBlob() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -16,6 +16,7 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -23,6 +24,8 @@ import (
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/sirupsen/logrus"
 )
 
 var ErrNoMorePages = errors.New("no more pages")
@@ -84,6 +87,14 @@ func WithDisableSSL() RegistryOption {
 	}
 }
 
+// WithoutDockerConfig disables reading the credentials from the docker config
+// when none are passed.
+func WithoutDockerConfig() RegistryOption {
+	return func(rc *RegistryClient) {
+		rc.dockerConfig = false
+	}
+}
+
 func WithBlobsDir(path string) RegistryOption {
 	return func(rc *RegistryClient) {
 		rc.layersDir = path
@@ -94,6 +105,11 @@ type RegistryClient struct {
 	cred        Credentials
 	sessionCred Credentials
 
+	// dockerConfig reads the credentials of host from the docker config on
+	// the first authentication if no credentials are passed
+	dockerConfig bool
+	host         string
+
 	insecure bool
 	ssl      bool
 	url      string
@@ -102,11 +118,17 @@ type RegistryClient struct {
 }
 
 func NewRegistryClient(url string, opts ...RegistryOption) *RegistryClient {
-	rc := &RegistryClient{ssl: true}
+	rc := &RegistryClient{ssl: true, dockerConfig: true}
 	for _, opt := range opts {
 		opt(rc)
 	}
 
+	rc.host = url
+	if _, host, ok := strings.Cut(url, "://"); ok {
+		rc.host = host
+	}
+	rc.host = strings.TrimSuffix(rc.host, "/")
+
 	if !strings.HasPrefix(url, "http") {
 		pfx := "http"
 		if rc.ssl {
@@ -121,6 +143,17 @@ func NewRegistryClient(url string, opts ...RegistryOption) *RegistryClient {
 }
 
 func (rc *RegistryClient) auth(hdr http.Header) error {
+	if rc.cred == nil && rc.dockerConfig {
+		// a broken docker config must not break the anonymous access
+		cred, err := DockerCredentials(rc.host)
+		if err != nil {
+			logrus.Warnf("Reading the credentials of %s from the docker config failed, continuing without them: %v",
+				rc.host, err)
+		} else {
+			rc.cred = cred
+		}
+		rc.dockerConfig = false
+	}
 	rc.sessionCred = rc.cred
 
 	var challenges []*challenge
@@ -196,17 +229,22 @@ func (rc *RegistryClient) repoUrl(repo string) string {
 	return u + repo
 }
 
+// get sends the request with a short overall timeout.
 func (rc *RegistryClient) get(url string, header http.Header) (*http.Response, error) {
-	opts := []requestOption{
-		RequestTimeout(5 * time.Second),
-		RequestHeader(header),
-	}
+	return rc.getContext(context.Background(), url, header, RequestTimeout(5*time.Second))
+}
+
+// getContext sends the request bound to ctx. Without the RequestTimeout
+// option it is limited by ctx only.
+func (rc *RegistryClient) getContext(ctx context.Context, url string, header http.Header, opts ...requestOption) (*http.Response, error) {
+	opts = append(opts, RequestHeader(header), RequestContext(ctx))
 
 	resp, err := Request.Get(url, append(opts, RequestCredentials(rc.sessionCred))...)
 	if err != nil {
 		return nil, err
 	}
 	if resp.StatusCode == http.StatusUnauthorized {
+		_ = resp.Body.Close()
 		if err = rc.auth(resp.Header); err != nil {
 			return nil, fmt.Errorf("could not authenticate: %v", err)
 		}
@@ -242,6 +280,25 @@ func (rc *RegistryClient) Blob(repo, tag, mediaType string) ([]byte, error) {
 	return rc.resp(u, mediaType)
 }
 
+// BlobReader streams the blob. Unlike Blob it is not limited by the request
+// timeout, so it suits image layers.
+func (rc *RegistryClient) BlobReader(ctx context.Context, repo, digest string) (io.ReadCloser, error) {
+	u := fmt.Sprintf("%s/blobs/%s", rc.repoUrl(repo), digest)
+
+	resp, err := rc.getContext(ctx, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("invalid status code %d (%s): %s", resp.StatusCode, resp.Status, b)
+	}
+
+	return resp.Body, nil
+}
+
 func (rc *RegistryClient) Manifest(repo, tag, mediaType string) ([]byte, error) {
 	u := fmt.Sprintf("%s/manifests/%s", rc.repoUrl(repo), tag)
 	return rc.resp(u, mediaType)
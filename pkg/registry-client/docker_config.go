@@ -0,0 +1,61 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package client
+
+import (
+	"fmt"
+
+	"github.com/docker/cli/cli/config"
+)
+
+// dockerIndexServer is the key of Docker Hub credentials in the docker config.
+const dockerIndexServer = "https://index.docker.io/v1/"
+
+func dockerConfigHost(host string) string {
+	switch host {
+	case "docker.io", "index.docker.io", "registry-1.docker.io":
+		return dockerIndexServer
+	}
+	return host
+}
+
+// DockerCredentials returns the credentials of the registry host stored in
+// the docker config (~/.docker/config.json or $DOCKER_CONFIG). The auths
+// section, credsStore and credHelpers are supported, the latter two run the
+// docker-credential-* helpers. Returns nil if no credentials are stored.
+func DockerCredentials(host string) (Credentials, error) {
+	return dockerCredentials(config.Dir(), host)
+}
+
+func dockerCredentials(configDir, host string) (Credentials, error) {
+	cf, err := config.Load(configDir)
+	if err != nil {
+		return nil, err
+	}
+
+	ac, err := cf.GetAuthConfig(dockerConfigHost(host))
+	if err != nil {
+		return nil, fmt.Errorf("could not get credentials of %s from the docker config: %v", host, err)
+	}
+
+	switch {
+	case len(ac.RegistryToken) > 0:
+		return NewAuthToken(ac.RegistryToken, ""), nil
+	case len(ac.Username) > 0:
+		return NewAuthBasic(ac.Username, ac.Password), nil
+	}
+	return nil, nil
+}
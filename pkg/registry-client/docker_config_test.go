@@ -0,0 +1,91 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package client
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testDockerConfig = `{
+  "auths": {
+    "https://index.docker.io/v1/": {"auth": "aHViOnNlY3JldA=="},
+    "hub.arenadata.io": {"registrytoken": "token"}
+  },
+  "credHelpers": {
+    "private.example.com": "test"
+  }
+}`
+
+// testCredentialHelper answers the get request of docker-credential-helpers.
+const testCredentialHelper = `#!/bin/sh
+read -r server
+echo '{"ServerURL":"'"$server"'","Username":"helper","Secret":"helper-secret"}'
+`
+
+func TestDockerCredentials(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(testDockerConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "docker-credential-test"), []byte(testCredentialHelper), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	tests := []struct {
+		name string
+		host string
+		want Credentials
+	}{
+		{"DockerHub", "docker.io", NewAuthBasic("hub", "secret")},
+		{"Token", "hub.arenadata.io", NewAuthToken("token", "")},
+		{"Helper", "private.example.com", NewAuthBasic("helper", "helper-secret")},
+		{"NotFound", "other.example.com", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := dockerCredentials(dir, tt.host)
+			if err != nil {
+				t.Fatalf("dockerCredentials() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("dockerCredentials() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthBrokenDockerConfig(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte("{broken"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("DOCKER_CONFIG", dir)
+
+	rc := NewRegistryClient("registry.example.com")
+	hdr := http.Header{}
+	hdr.Set("WWW-Authenticate", `Basic realm="registry"`)
+	if err := rc.auth(hdr); err != nil {
+		t.Fatalf("auth() error = %v", err)
+	}
+	if rc.cred != nil {
+		t.Errorf("auth() credentials = %v, want none", rc.cred)
+	}
+}
@@ -86,10 +86,8 @@ func newRequest(method, url string, opts ...requestOption) (*http.Response, erro
 		opt(r)
 	}
 
-	var nilCtx bool
 	if r.ctx == nil {
 		r.ctx = context.Background()
-		nilCtx = true
 	}
 
 	req, err := http.NewRequestWithContext(r.ctx, method, url, r.body)
@@ -105,10 +103,7 @@ func newRequest(method, url string, opts ...requestOption) (*http.Response, erro
 		req.Header[k] = v
 	}
 
-	client := new(http.Client)
-	if nilCtx {
-		client.Timeout = r.timeout
-	}
+	client := &http.Client{Timeout: r.timeout}
 	if r.insecure {
 		client.Transport = &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
 	}
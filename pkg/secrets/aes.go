@@ -19,16 +19,33 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"strings"
 )
 
-const sep = "."
+const (
+	sep = "."
+
+	// envelopeV2 is a versioned format of encrypted values:
+	// v2:<key id>:<base64 nonce>:<base64 ciphertext>. The value context
+	// (service name and secret key) is bound to the ciphertext as AES-GCM
+	// additional authenticated data.
+	envelopeV2    = "v2"
+	envelopeSep   = ":"
+	keyIdLength   = 8
+	aadSeparator  = "\x00"
+	keyIdHashSalt = "adi-aes-key-id"
+	macKeySalt    = "adi-mac-key"
+)
 
 type AesCrypt struct {
-	c   cipher.Block
-	gcm cipher.AEAD
+	c      cipher.Block
+	gcm    cipher.AEAD
+	keyId  string
+	macKey []byte
 }
 
 func NewAesCrypt(key []byte) (*AesCrypt, error) {
@@ -42,24 +59,85 @@ func NewAesCrypt(key []byte) (*AesCrypt, error) {
 		return nil, err
 	}
 
-	return &AesCrypt{c: c, gcm: gcm}, nil
+	sum := sha256.Sum256(append([]byte(keyIdHashSalt), key...))
+	keyId := hex.EncodeToString(sum[:])[:keyIdLength]
+
+	macKey := sha256.Sum256(append([]byte(macKeySalt), key...))
+
+	return &AesCrypt{c: c, gcm: gcm, keyId: keyId, macKey: macKey[:]}, nil
+}
+
+// KeyId returns a short non-secret identifier of the key.
+func (c *AesCrypt) KeyId() string {
+	return c.keyId
 }
 
-func (c *AesCrypt) EncryptValue(v string) (string, error) {
+// MacKey returns a key derived from the encryption key, suitable for
+// fingerprinting secret values without disclosing them.
+func (c *AesCrypt) MacKey() []byte {
+	return c.macKey
+}
+
+func additionalData(context []string) []byte {
+	if len(context) == 0 {
+		return nil
+	}
+	return []byte(strings.Join(context, aadSeparator))
+}
+
+// IsLegacyValue reports whether v is encrypted in the unversioned format.
+func IsLegacyValue(v string) bool {
+	return !strings.HasPrefix(v, envelopeV2+envelopeSep)
+}
+
+func (c *AesCrypt) EncryptValue(v string, context ...string) (string, error) {
 	nonce := make([]byte, c.gcm.NonceSize())
 	if _, err := rand.Read(nonce); err != nil {
 		return "", err
 	}
 
-	ciphertext := c.gcm.Seal(nil, nonce, []byte(v), nil)
+	ciphertext := c.gcm.Seal(nil, nonce, []byte(v), additionalData(context))
 
 	return strings.Join([]string{
-		base64.StdEncoding.EncodeToString(ciphertext),
+		envelopeV2,
+		c.keyId,
 		base64.StdEncoding.EncodeToString(nonce),
-	}, sep), nil
+		base64.StdEncoding.EncodeToString(ciphertext),
+	}, envelopeSep), nil
+}
+
+func (c *AesCrypt) DecryptValue(v string, context ...string) (string, error) {
+	if IsLegacyValue(v) {
+		return c.decryptLegacyValue(v)
+	}
+
+	parts := strings.Split(v, envelopeSep)
+	if len(parts) != 4 {
+		return "", fmt.Errorf("invalid encrypted value format")
+	}
+
+	if parts[1] != c.keyId {
+		return "", fmt.Errorf("value is encrypted with another key (key id %s)", parts[1])
+	}
+
+	nonce, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", err
+	}
+
+	data, err := base64.StdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return "", err
+	}
+
+	b, err := c.gcm.Open(nil, nonce, data, additionalData(context))
+	if err != nil {
+		return "", fmt.Errorf("decrypt value %s failed: %v", strings.Join(context, "."), err)
+	}
+	return string(b), nil
 }
 
-func (c *AesCrypt) DecryptValue(v string) (string, error) {
+func (c *AesCrypt) decryptLegacyValue(v string) (string, error) {
 	parts := strings.Split(v, sep)
 	if len(parts) != 2 {
 		return "", fmt.Errorf("invalid encrypted value format")
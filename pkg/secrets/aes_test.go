@@ -0,0 +1,116 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package secrets
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func newTestAesCrypt(t *testing.T) *AesCrypt {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewAesCrypt(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func legacyEncryptValue(t *testing.T, c *AesCrypt, v string) string {
+	nonce := make([]byte, c.gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		t.Fatal(err)
+	}
+	ciphertext := c.gcm.Seal(nil, nonce, []byte(v), nil)
+
+	return base64.StdEncoding.EncodeToString(ciphertext) + sep + base64.StdEncoding.EncodeToString(nonce)
+}
+
+func TestAesCrypt_EncryptValue(t *testing.T) {
+	c := newTestAesCrypt(t)
+
+	enc, err := c.EncryptValue("secret", "adcm", "db-pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(enc, envelopeV2+envelopeSep+c.KeyId()+envelopeSep) {
+		t.Errorf("EncryptValue() = %s, want prefix v2:%s:", enc, c.KeyId())
+	}
+	if IsLegacyValue(enc) {
+		t.Errorf("IsLegacyValue(%s) = true, want false", enc)
+	}
+}
+
+func TestAesCrypt_DecryptValue(t *testing.T) {
+	c := newTestAesCrypt(t)
+	other := newTestAesCrypt(t)
+
+	v2, err := c.EncryptValue("secret", "adcm", "db-pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy := legacyEncryptValue(t, c, "secret")
+
+	tests := []struct {
+		name    string
+		crypt   *AesCrypt
+		value   string
+		context []string
+		wantErr bool
+	}{
+		{"V2", c, v2, []string{"adcm", "db-pass"}, false},
+		{"V2AnotherService", c, v2, []string{"vault", "db-pass"}, true},
+		{"V2AnotherKey", c, v2, []string{"adcm", "db-user"}, true},
+		{"V2NoContext", c, v2, nil, true},
+		{"V2AnotherAesKey", other, v2, []string{"adcm", "db-pass"}, true},
+		{"Legacy", c, legacy, []string{"adcm", "db-pass"}, false},
+		{"LegacyNoContext", c, legacy, nil, false},
+		{"InvalidFormat", c, "v2:abc", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.crypt.DecryptValue(tt.value, tt.context...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DecryptValue() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && got != "secret" {
+				t.Errorf("DecryptValue() got = %v, want %v", got, "secret")
+			}
+		})
+	}
+}
+
+func TestAesCrypt_KeyId(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	c1, _ := NewAesCrypt(key)
+	c2, _ := NewAesCrypt(key)
+
+	if c1.KeyId() != c2.KeyId() {
+		t.Errorf("KeyId() is not stable: %s != %s", c1.KeyId(), c2.KeyId())
+	}
+	if len(c1.KeyId()) != keyIdLength {
+		t.Errorf("KeyId() length = %d, want %d", len(c1.KeyId()), keyIdLength)
+	}
+}
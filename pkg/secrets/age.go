@@ -18,15 +18,32 @@ package secrets
 import (
 	"bytes"
 	"encoding/base64"
+	"errors"
+	"fmt"
 	"io"
 	"strings"
 
 	"filippo.io/age"
+	"filippo.io/age/agessh"
 	"filippo.io/age/armor"
+	"golang.org/x/crypto/ssh"
 )
 
+const (
+	ageSecretKeyPrefix = "AGE-SECRET-KEY-"
+	ageEncryptedHeader = "age-encryption.org/v1"
+	sshKeyPrefix       = "ssh-"
+)
+
+// PassphraseFunc returns the passphrase protecting an identity. It is called
+// only when an encrypted identity is found.
+type PassphraseFunc func() (string, error)
+
 type AgeCrypt struct {
-	*age.X25519Identity
+	identity  age.Identity
+	recipient age.Recipient
+	publicKey string
+	secretKey string
 }
 
 func NewAgeCrypt() (*AgeCrypt, error) {
@@ -34,20 +51,142 @@ func NewAgeCrypt() (*AgeCrypt, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &AgeCrypt{id}, nil
+	return newX25519AgeCrypt(id), nil
+}
+
+func newX25519AgeCrypt(id *age.X25519Identity) *AgeCrypt {
+	return &AgeCrypt{
+		identity:  id,
+		recipient: id.Recipient(),
+		publicKey: id.Recipient().String(),
+		secretKey: id.String(),
+	}
 }
 
 func NewAgeCryptFromString(s string) (*AgeCrypt, error) {
-	id, err := age.ParseX25519Identity(s)
+	return ParseAgeIdentity([]byte(s), nil)
+}
+
+// ParseAgeIdentity parses an age identity file content. Supported formats are
+// plain age X25519 identities, age identity files encrypted with a passphrase
+// (scrypt) and OpenSSH ed25519/rsa private keys, optionally protected with a
+// passphrase.
+func ParseAgeIdentity(data []byte, passphrase PassphraseFunc) (*AgeCrypt, error) {
+	s := strings.TrimSpace(string(data))
+
+	switch {
+	case strings.HasPrefix(s, armor.Header):
+		return parseEncryptedAgeIdentity(armor.NewReader(strings.NewReader(s)), passphrase)
+	case bytes.HasPrefix(data, []byte(ageEncryptedHeader)):
+		return parseEncryptedAgeIdentity(bytes.NewReader(data), passphrase)
+	case strings.Contains(s, "PRIVATE KEY-----"):
+		return parseSSHIdentity([]byte(s), passphrase)
+	}
+
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		id, err := age.ParseX25519Identity(line)
+		if err != nil {
+			return nil, err
+		}
+		return newX25519AgeCrypt(id), nil
+	}
+
+	return nil, fmt.Errorf("no age key found")
+}
+
+func parseEncryptedAgeIdentity(r io.Reader, passphrase PassphraseFunc) (*AgeCrypt, error) {
+	if passphrase == nil {
+		return nil, fmt.Errorf("age key is encrypted with a passphrase, but no passphrase provided")
+	}
+
+	pass, err := passphrase()
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := age.NewScryptIdentity(pass)
+	if err != nil {
+		return nil, err
+	}
+
+	dr, err := age.Decrypt(r, id)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt age key failed: %v", err)
+	}
+
+	b, err := io.ReadAll(dr)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasPrefix(strings.TrimSpace(string(b)), armor.Header) || bytes.HasPrefix(b, []byte(ageEncryptedHeader)) {
+		return nil, fmt.Errorf("nested encrypted age keys are not supported")
+	}
+
+	return ParseAgeIdentity(b, passphrase)
+}
+
+func parseSSHIdentity(pemBytes []byte, passphrase PassphraseFunc) (*AgeCrypt, error) {
+	id, err := agessh.ParseIdentity(pemBytes)
+	if err == nil {
+		signer, err := ssh.ParsePrivateKey(pemBytes)
+		if err != nil {
+			return nil, err
+		}
+		return newSSHAgeCrypt(id, signer.PublicKey())
+	}
+
+	var missingErr *ssh.PassphraseMissingError
+	if !errors.As(err, &missingErr) {
+		return nil, err
+	}
+	if passphrase == nil {
+		return nil, fmt.Errorf("ssh key is encrypted with a passphrase, but no passphrase provided")
+	}
+	if missingErr.PublicKey == nil {
+		return nil, fmt.Errorf("encrypted ssh key has no public key, convert it to the OpenSSH format")
+	}
+
+	encId, err := agessh.NewEncryptedSSHIdentity(missingErr.PublicKey, pemBytes, func() ([]byte, error) {
+		pass, err := passphrase()
+		return []byte(pass), err
+	})
 	if err != nil {
 		return nil, err
 	}
-	return &AgeCrypt{id}, nil
+
+	return newSSHAgeCrypt(encId, missingErr.PublicKey)
+}
+
+func newSSHAgeCrypt(id age.Identity, pub ssh.PublicKey) (*AgeCrypt, error) {
+	publicKey := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(pub)))
+	recipient, err := agessh.ParseRecipient(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AgeCrypt{identity: id, recipient: recipient, publicKey: publicKey}, nil
+}
+
+// Recipient returns the public key of the identity: an age1... string for
+// age keys and an authorized_keys line without comment for ssh keys.
+func (c *AgeCrypt) Recipient() string {
+	return c.publicKey
+}
+
+// String returns the secret key. It is empty for ssh based identities.
+func (c *AgeCrypt) String() string {
+	return c.secretKey
 }
 
 func (c *AgeCrypt) EncryptValue(v string) (string, error) {
 	buf := new(bytes.Buffer)
-	w, err := age.Encrypt(buf, c.Recipient())
+	w, err := age.Encrypt(buf, c.recipient)
 	if err != nil {
 		return "", err
 	}
@@ -62,10 +201,73 @@ func (c *AgeCrypt) EncryptValue(v string) (string, error) {
 }
 
 func (c *AgeCrypt) Encrypt(data string) (string, error) {
+	return encryptArmored(data, c.recipient)
+}
+
+// NormalizeRecipient validates an age or ssh public key and returns it in the
+// canonical form used in x-secrets.
+func NormalizeRecipient(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, sshKeyPrefix) {
+		if _, err := age.ParseX25519Recipient(s); err != nil {
+			return "", err
+		}
+		return s, nil
+	}
+
+	pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(s))
+	if err != nil {
+		return "", err
+	}
+	if _, err = agessh.ParseRecipient(s); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(pub))), nil
+}
+
+func parseRecipient(s string) (age.Recipient, error) {
+	if strings.HasPrefix(s, sshKeyPrefix) {
+		return agessh.ParseRecipient(s)
+	}
+	return age.ParseX25519Recipient(s)
+}
+
+// EncryptForRecipients encrypts data to every recipient in the list, so that
+// any of the matching identities is able to decrypt it.
+func EncryptForRecipients(data string, recipients ...string) (string, error) {
+	if len(recipients) == 0 {
+		return "", fmt.Errorf("no age recipients provided")
+	}
+
+	var rcpts []age.Recipient
+	for _, r := range recipients {
+		rcpt, err := parseRecipient(r)
+		if err != nil {
+			return "", fmt.Errorf("parse recipient %q failed: %v", r, err)
+		}
+		rcpts = append(rcpts, rcpt)
+	}
+
+	return encryptArmored(data, rcpts...)
+}
+
+// EncryptWithPassphrase encrypts data with a scrypt passphrase recipient. It is
+// used to protect age identity files at rest.
+func EncryptWithPassphrase(data, passphrase string) (string, error) {
+	rcpt, err := age.NewScryptRecipient(passphrase)
+	if err != nil {
+		return "", err
+	}
+
+	return encryptArmored(data, rcpt)
+}
+
+func encryptArmored(data string, recipients ...age.Recipient) (string, error) {
 	buf := new(bytes.Buffer)
 	aw := armor.NewWriter(buf)
 
-	w, err := age.Encrypt(aw, c.Recipient())
+	w, err := age.Encrypt(aw, recipients...)
 	if err != nil {
 		return "", err
 	}
@@ -88,7 +290,7 @@ func (c *AgeCrypt) DecryptValue(v string) (string, error) {
 		return "", err
 	}
 
-	r, err := age.Decrypt(bytes.NewReader(data), c)
+	r, err := age.Decrypt(bytes.NewReader(data), c.identity)
 	if err != nil {
 		return "", err
 	}
@@ -104,7 +306,7 @@ func (c *AgeCrypt) DecryptValue(v string) (string, error) {
 func (c *AgeCrypt) Decrypt(data string) (string, error) {
 	ar := armor.NewReader(strings.NewReader(data))
 
-	r, err := age.Decrypt(ar, c)
+	r, err := age.Decrypt(ar, c.identity)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,176 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package secrets
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func passphraseFunc(pass string) PassphraseFunc {
+	return func() (string, error) { return pass, nil }
+}
+
+func newTestSSHKey(t *testing.T, passphrase string) ([]byte, string) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var block *pem.Block
+	if len(passphrase) > 0 {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(priv, "", []byte(passphrase))
+	} else {
+		block, err = ssh.MarshalPrivateKey(priv, "")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(block), strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPub)))
+}
+
+func TestParseAgeIdentity(t *testing.T) {
+	key, err := NewAgeCrypt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain := "# created: 2025-01-01T00:00:00Z\n# public key: " + key.Recipient() + "\n" + key.String() + "\n"
+	scrypt, err := EncryptWithPassphrase(plain, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshKey, sshPub := newTestSSHKey(t, "")
+	sshEncKey, sshEncPub := newTestSSHKey(t, "secret")
+
+	tests := []struct {
+		name       string
+		data       string
+		passphrase PassphraseFunc
+		want       string
+		wantError  bool
+	}{
+		{"Plain", plain, nil, key.Recipient(), false},
+		{"Scrypt", scrypt, passphraseFunc("secret"), key.Recipient(), false},
+		{"ScryptWrongPassphrase", scrypt, passphraseFunc("wrong"), "", true},
+		{"ScryptNoPassphrase", scrypt, nil, "", true},
+		{"SSH", string(sshKey), nil, sshPub, false},
+		{"EncryptedSSH", string(sshEncKey), passphraseFunc("secret"), sshEncPub, false},
+		{"EncryptedSSHNoPassphrase", string(sshEncKey), nil, "", true},
+		{"NoKey", "# comment only\n", nil, "", true},
+		{"Invalid", "AGE-SECRET-KEY-INVALID", nil, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseAgeIdentity([]byte(tt.data), tt.passphrase)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("error = %v, wantError %v", err, tt.wantError)
+			}
+			if err != nil {
+				return
+			}
+			if got.Recipient() != tt.want {
+				t.Errorf("Recipient() = %q, want %q", got.Recipient(), tt.want)
+			}
+
+			enc, err := got.EncryptValue("value")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if dec, err := got.DecryptValue(enc); err != nil || dec != "value" {
+				t.Errorf("DecryptValue() = %q, %v", dec, err)
+			}
+		})
+	}
+}
+
+func TestParseAgeIdentity_EncryptedSSHWrongPassphrase(t *testing.T) {
+	sshKey, _ := newTestSSHKey(t, "secret")
+
+	// the passphrase of an ssh key is asked on the first decryption
+	id, err := ParseAgeIdentity(sshKey, passphraseFunc("wrong"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, err := id.EncryptValue("value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = id.DecryptValue(enc); err == nil {
+		t.Error("decrypted with a wrong passphrase")
+	}
+}
+
+func TestParseAgeIdentity_PassphraseError(t *testing.T) {
+	sshKey, _ := newTestSSHKey(t, "secret")
+	askErr := errors.New("no terminal")
+
+	id, err := ParseAgeIdentity(sshKey, func() (string, error) { return "", askErr })
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, err := id.EncryptValue("value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = id.DecryptValue(enc); err == nil || !strings.Contains(err.Error(), askErr.Error()) {
+		t.Errorf("error = %v, want %v", err, askErr)
+	}
+}
+
+func TestNormalizeRecipient(t *testing.T) {
+	key, err := NewAgeCrypt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, sshPub := newTestSSHKey(t, "")
+
+	tests := []struct {
+		name      string
+		recipient string
+		want      string
+		wantError bool
+	}{
+		{"Age", key.Recipient(), key.Recipient(), false},
+		{"AgeSpaces", "  " + key.Recipient() + "\n", key.Recipient(), false},
+		{"SSH", sshPub, sshPub, false},
+		{"SSHComment", sshPub + " user@host", sshPub, false},
+		{"InvalidAge", "age1invalid", "", true},
+		{"InvalidSSH", "ssh-ed25519 AAAA", "", true},
+		{"Empty", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeRecipient(tt.recipient)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("error = %v, wantError %v", err, tt.wantError)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
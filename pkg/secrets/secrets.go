@@ -15,7 +15,11 @@
 
 package secrets
 
+// Secrets encrypts x-secrets values. The optional context (e.g. service name
+// and secret key) is bound to the encrypted value, so it cannot be moved to
+// another place of the configuration file unnoticed.
 type Secrets interface {
-	EncryptValue(v string) (string, error)
-	DecryptValue(v string) (string, error)
+	EncryptValue(v string, context ...string) (string, error)
+	DecryptValue(v string, context ...string) (string, error)
+	MacKey() []byte
 }
@@ -25,15 +25,34 @@ import (
 	"github.com/openbao/openbao/api/v2"
 )
 
+type ApiOption func(*api.Config) error
+
+// WithTLS verifies the certificate of the Vault listener with the PEM encoded
+// CA certificate and the server name. The system roots are used without the
+// CA certificate.
+func WithTLS(caCert []byte, serverName string) ApiOption {
+	return func(conf *api.Config) error {
+		return conf.ConfigureTLS(&api.TLSConfig{
+			CACertBytes:   caCert,
+			TLSServerName: serverName,
+		})
+	}
+}
+
 type Api struct {
 	client *api.Client
 }
 
-func New(addr string) (*Api, error) {
+func New(addr string, opts ...ApiOption) (*Api, error) {
 	conf := api.DefaultConfig()
 	if len(addr) > 0 {
 		conf.Address = addr
 	}
+	for _, opt := range opts {
+		if err := opt(conf); err != nil {
+			return nil, err
+		}
+	}
 
 	client, err := api.NewClient(conf)
 	if err != nil {
@@ -43,10 +62,16 @@ func New(addr string) (*Api, error) {
 	return &Api{client: client}, nil
 }
 
-func (a *Api) Init(ctx context.Context) (*unseal.VaultInitData, error) {
+func (a *Api) Init(ctx context.Context, opts unseal.InitOptions) (*unseal.VaultInitData, error) {
 	req := &api.InitRequest{
-		SecretShares:    5,
-		SecretThreshold: 3,
+		SecretShares:    opts.Shares(),
+		SecretThreshold: opts.Threshold(),
+	}
+	if opts.Recovery {
+		req = &api.InitRequest{
+			RecoveryShares:    opts.Shares(),
+			RecoveryThreshold: opts.Threshold(),
+		}
 	}
 
 	resp, err := a.client.Sys().InitWithContext(ctx, req)
@@ -69,8 +94,8 @@ func (a *Api) Init(ctx context.Context) (*unseal.VaultInitData, error) {
 	return r, nil
 }
 
-func (a *Api) RawInitData(ctx context.Context) ([]byte, error) {
-	r, err := a.Init(ctx)
+func (a *Api) RawInitData(ctx context.Context, opts unseal.InitOptions) ([]byte, error) {
+	r, err := a.Init(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -84,7 +109,23 @@ func (a *Api) Status(ctx context.Context) (*unseal.SealStatusResponse, error) {
 		return nil, err
 	}
 
-	return (*unseal.SealStatusResponse)(r), nil
+	return &unseal.SealStatusResponse{
+		Type:         r.Type,
+		Initialized:  r.Initialized,
+		Sealed:       r.Sealed,
+		T:            r.T,
+		N:            r.N,
+		Progress:     r.Progress,
+		Nonce:        r.Nonce,
+		Version:      r.Version,
+		BuildDate:    r.BuildDate,
+		Migration:    r.Migration,
+		ClusterName:  r.ClusterName,
+		ClusterID:    r.ClusterID,
+		RecoverySeal: r.RecoverySeal,
+		StorageType:  r.StorageType,
+		Warnings:     r.Warnings,
+	}, nil
 }
 
 func (a *Api) Unseal(ctx context.Context, keys []string) error {
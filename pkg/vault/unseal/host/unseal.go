@@ -26,17 +26,33 @@ import (
 	"github.com/arenadata/adcm-installer/pkg/vault/unseal"
 )
 
+type HostOption func(*Host)
+
+// WithEnv sets environment variables of the vault/bao commands, e.g. the
+// address of the Vault API.
+func WithEnv(env ...string) HostOption {
+	return func(h *Host) {
+		h.env = append(h.env, env...)
+	}
+}
+
 type Host struct {
 	bin string
+	env []string
 }
 
-func New() (unseal.Runner, error) {
+func New(opts ...HostOption) (unseal.Runner, error) {
 	bin, err := lookupBinPath()
 	if err != nil {
 		return nil, err
 	}
 
-	return &Host{bin: bin}, nil
+	h := &Host{bin: bin}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h, nil
 }
 
 func lookupBinPath() (string, error) {
@@ -51,7 +67,8 @@ func lookupBinPath() (string, error) {
 
 func (h *Host) cmd(ctx context.Context, args ...string) *exec.Cmd {
 	cmd := exec.CommandContext(ctx, h.bin, args...)
-	cmd.Env = append(os.Environ(), unseal.EnvFormatJson)
+	cmd.Env = append(os.Environ(), h.env...)
+	cmd.Env = append(cmd.Env, unseal.EnvFormatJson)
 	return cmd
 }
 
@@ -79,16 +96,16 @@ func (h *Host) Status(ctx context.Context) (*unseal.SealStatusResponse, error) {
 	return h.unmarshalStatus(resp)
 }
 
-func (h *Host) RawInitData(ctx context.Context) ([]byte, error) {
-	resp, err := h.cmd(ctx, "operator", "init").Output()
+func (h *Host) RawInitData(ctx context.Context, opts unseal.InitOptions) ([]byte, error) {
+	resp, err := h.cmd(ctx, append([]string{"operator", "init"}, opts.Args()...)...).Output()
 	if err != nil {
 		return nil, fmt.Errorf("call command failed: %v", err)
 	}
 	return resp, nil
 }
 
-func (h *Host) Init(ctx context.Context) (*unseal.VaultInitData, error) {
-	resp, err := h.RawInitData(ctx)
+func (h *Host) Init(ctx context.Context, opts unseal.InitOptions) (*unseal.VaultInitData, error) {
+	resp, err := h.RawInitData(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
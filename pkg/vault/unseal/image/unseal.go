@@ -125,16 +125,16 @@ func (c *Container) Status(ctx context.Context) (*unseal.SealStatusResponse, err
 	return c.unmarshalStatus()
 }
 
-func (c *Container) RawInitData(ctx context.Context) ([]byte, error) {
+func (c *Container) RawInitData(ctx context.Context, opts unseal.InitOptions) ([]byte, error) {
 	defer c.buf.Reset()
-	if err := c.init(ctx); err != nil {
+	if err := c.init(ctx, opts); err != nil {
 		return nil, err
 	}
 	return c.buf.Bytes(), nil
 }
 
-func (c *Container) init(ctx context.Context) error {
-	c.opts.Command = []string{c.bin, "operator", "init"}
+func (c *Container) init(ctx context.Context, opts unseal.InitOptions) error {
+	c.opts.Command = append([]string{c.bin, "operator", "init"}, opts.Args()...)
 	err := container.RunExec(ctx, c.cli, c.name, c.opts)
 	if err != nil {
 		return fmt.Errorf("init: call command failed: %v", err)
@@ -142,8 +142,8 @@ func (c *Container) init(ctx context.Context) error {
 	return nil
 }
 
-func (c *Container) Init(ctx context.Context) (*unseal.VaultInitData, error) {
-	if err := c.init(ctx); err != nil {
+func (c *Container) Init(ctx context.Context, opts unseal.InitOptions) (*unseal.VaultInitData, error) {
+	if err := c.init(ctx, opts); err != nil {
 		return nil, err
 	}
 
@@ -0,0 +1,91 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+// Package runner creates the unseal.Runner of the selected backend.
+package runner
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/arenadata/adcm-installer/pkg/vault/unseal"
+	"github.com/arenadata/adcm-installer/pkg/vault/unseal/api"
+	"github.com/arenadata/adcm-installer/pkg/vault/unseal/host"
+	"github.com/arenadata/adcm-installer/pkg/vault/unseal/image"
+)
+
+const (
+	// ViaContainer runs the bao binary of the Vault container
+	ViaContainer = "container"
+	// ViaHost runs the vault/bao binary of the host against the published port
+	ViaHost = "host"
+	// ViaApi calls the HTTP API on the published port
+	ViaApi = "api"
+)
+
+var Backends = []string{ViaContainer, ViaHost, ViaApi}
+
+// Target is the Vault node to run the commands against.
+type Target struct {
+	// Container is the name of the node container
+	Container string
+	// Address is the URL of the published Vault API, used by the host and
+	// the api backends
+	Address string
+	// CACert is the PEM encoded CA certificate of the listener
+	CACert []byte
+	// ServerName is the name the listener certificate is verified with
+	ServerName string
+}
+
+// New returns the runner of the backend for the target.
+func New(via string, target Target) (unseal.Runner, error) {
+	switch via {
+	case "", ViaContainer:
+		if len(target.Container) == 0 {
+			return nil, fmt.Errorf("%s: no container", via)
+		}
+		return image.New(target.Container)
+
+	case ViaHost:
+		if len(target.Address) == 0 {
+			return nil, fmt.Errorf("%s: no address", via)
+		}
+		var env []string
+		for _, prefix := range []string{"BAO_", "VAULT_"} {
+			env = append(env, prefix+"ADDR="+target.Address)
+			if len(target.CACert) > 0 {
+				env = append(env, prefix+"CACERT_BYTES="+string(target.CACert))
+			}
+			if len(target.ServerName) > 0 {
+				env = append(env, prefix+"TLS_SERVER_NAME="+target.ServerName)
+			}
+		}
+		return host.New(host.WithEnv(env...))
+
+	case ViaApi:
+		if len(target.Address) == 0 {
+			return nil, fmt.Errorf("%s: no address", via)
+		}
+		return api.New(target.Address, api.WithTLS(target.CACert, target.ServerName))
+	}
+
+	return nil, fmt.Errorf("unknown unseal backend %q, one of %v is expected", via, Backends)
+}
+
+// Valid reports whether the backend is known, empty means the default one.
+func Valid(via string) bool {
+	return len(via) == 0 || slices.Contains(Backends, via)
+}
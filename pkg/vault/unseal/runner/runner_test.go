@@ -0,0 +1,57 @@
+/*
+ Copyright (c) 2025 Arenadata Softwer LLC.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package runner
+
+import (
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		via     string
+		target  Target
+		wantErr bool
+	}{
+		{"Unknown", "ssh", Target{Container: "vault"}, true},
+		{"ContainerWithoutName", ViaContainer, Target{Address: "http://127.0.0.1:8200"}, true},
+		{"HostWithoutAddress", ViaHost, Target{Container: "vault"}, true},
+		{"ApiWithoutAddress", ViaApi, Target{Container: "vault"}, true},
+		{"Api", ViaApi, Target{Address: "http://127.0.0.1:8200"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := New(tt.via, tt.target)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && r == nil {
+				t.Error("New() returned no runner")
+			}
+		})
+	}
+}
+
+func TestValid(t *testing.T) {
+	for _, via := range append([]string{""}, Backends...) {
+		if !Valid(via) {
+			t.Errorf("Valid(%q) = false", via)
+		}
+	}
+	if Valid("ssh") {
+		t.Error(`Valid("ssh") = true`)
+	}
+}
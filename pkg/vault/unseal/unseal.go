@@ -18,9 +18,15 @@ package unseal
 import (
 	"context"
 	"errors"
+	"strconv"
 )
 
-const EnvFormatJson = "VAULT_FORMAT=json"
+const (
+	EnvFormatJson = "VAULT_FORMAT=json"
+
+	DefaultSecretShares    = 5
+	DefaultSecretThreshold = 3
+)
 
 var ErrVaultIsAlreadyInitialized = errors.New("vault is already initialized")
 
@@ -51,12 +57,56 @@ type SealStatusResponse struct {
 	ClusterID    string   `json:"cluster_id,omitempty"`
 	RecoverySeal bool     `json:"recovery_seal"`
 	StorageType  string   `json:"storage_type,omitempty"`
+	HAEnabled    bool     `json:"ha_enabled,omitempty"`
+	IsSelf       bool     `json:"is_self,omitempty"`
+	LeaderAddr   string   `json:"leader_address,omitempty"`
 	Warnings     []string `json:"warnings,omitempty"`
 }
 
+// SealTypeShamir is the seal type of Vault unsealed with the key shares. The
+// other seal types unseal Vault automatically.
+const SealTypeShamir = "shamir"
+
+// InitOptions are the parameters of the Vault initialization. Zero values are
+// replaced with the defaults. With Recovery the shares are the recovery keys
+// of an auto-unsealed Vault.
+type InitOptions struct {
+	SecretShares    int
+	SecretThreshold int
+	Recovery        bool
+}
+
+func (o InitOptions) Shares() int {
+	if o.SecretShares > 0 {
+		return o.SecretShares
+	}
+	return DefaultSecretShares
+}
+
+func (o InitOptions) Threshold() int {
+	if o.SecretThreshold > 0 {
+		return o.SecretThreshold
+	}
+	return DefaultSecretThreshold
+}
+
+// Args returns the arguments of the operator init command.
+func (o InitOptions) Args() []string {
+	if o.Recovery {
+		return []string{
+			"-recovery-shares=" + strconv.Itoa(o.Shares()),
+			"-recovery-threshold=" + strconv.Itoa(o.Threshold()),
+		}
+	}
+	return []string{
+		"-key-shares=" + strconv.Itoa(o.Shares()),
+		"-key-threshold=" + strconv.Itoa(o.Threshold()),
+	}
+}
+
 type Runner interface {
-	Init(context.Context) (*VaultInitData, error)
-	RawInitData(ctx context.Context) ([]byte, error)
+	Init(context.Context, InitOptions) (*VaultInitData, error)
+	RawInitData(context.Context, InitOptions) ([]byte, error)
 	Status(context.Context) (*SealStatusResponse, error)
 	Unseal(context.Context, []string) error
 }